@@ -0,0 +1,587 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"swap/internal/datatypes"
+	"swap/internal/utils"
+	"swap/pkg/logger"
+	"swap/service/history"
+	"swap/service/ledger"
+	"swap/service/token"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// runCommand dispatches a command line subcommand such as `history export`
+func runCommand(args []string) error {
+	switch args[0] {
+	case "history":
+		return runHistory(args[1:])
+	case "report":
+		return runReport(args[1:])
+	case "logs":
+		return runLogs(args[1:])
+	default:
+		return fmt.Errorf("unknown command %q (available: history, report, logs)", args[0])
+	}
+}
+
+// runHistory handles the `history` subcommands
+func runHistory(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: history <export|series|sync> [flags]")
+	}
+
+	switch args[0] {
+	case "export":
+		return historyExport(args[1:])
+	case "sync":
+		return historySync(args[1:])
+	case "series":
+		store, err := history.Open(utils.GetEnv("HISTORY_DIR", "history"), history.Config{})
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		series, err := store.Series()
+		if err != nil {
+			return err
+		}
+		for _, name := range series {
+			fmt.Println(name)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown history command %q (available: export, series, sync)", args[0])
+	}
+}
+
+// historyExport writes recorded candles as CSV or JSON
+func historyExport(args []string) error {
+	flags := flag.NewFlagSet("history export", flag.ContinueOnError)
+	dir := flags.String("dir", utils.GetEnv("HISTORY_DIR", "history"), "history directory")
+	pair := flags.String("pair", "SOL/USDC", "pair to export, e.g. SOL/USDC")
+	resolution := flags.String("resolution", "1m", "candle resolution: 1m, 5m or 1h")
+	format := flags.String("format", history.FormatCSV, "output format: csv or json")
+	since := flags.String("since", "", "only export candles from this time (RFC3339) or this long ago (e.g. 24h)")
+	limit := flags.Int("limit", 0, "only export the most recent N candles")
+	out := flags.String("out", "", "output file (default: stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	from, err := parseSince(*since)
+	if err != nil {
+		return err
+	}
+
+	store, err := history.Open(*dir, history.Config{})
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	candles, err := store.Candles(history.SeriesName(strings.ToUpper(*pair)), *resolution, from, *limit)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	return history.ExportCandles(w, *format, candles)
+}
+
+// historySync rebuilds the trade ledger from the wallet's on-chain Jupiter swaps
+func historySync(args []string) error {
+	flags := flag.NewFlagSet("history sync", flag.ContinueOnError)
+	stateDir := flags.String("state", utils.GetEnv("STATE_DIR", "state"), "state directory containing the ledger")
+	wallet := flags.String("wallet", "", "wallet address (default: derived from PRIVATE_KEY)")
+	limit := flags.Int("limit", 0, "only scan the most recent N transactions (default: all)")
+	before := flags.String("before", "", "start scanning before this transaction signature")
+	until := flags.String("until", "", "stop scanning at this transaction signature")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	owner, err := walletAddress(*wallet)
+	if err != nil {
+		return err
+	}
+	opts := ledger.SyncOptions{Limit: *limit}
+	if *before != "" {
+		if opts.Before, err = solana.SignatureFromBase58(*before); err != nil {
+			return fmt.Errorf("invalid -before signature: %v", err)
+		}
+	}
+	if *until != "" {
+		if opts.Until, err = solana.SignatureFromBase58(*until); err != nil {
+			return fmt.Errorf("invalid -until signature: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	client := rpc.New(utils.GetEnv("RPC_ENDPOINT", "https://api.mainnet-beta.solana.com"))
+	pairs, err := syncPairs(ctx, token.NewRegistry(client))
+	if err != nil {
+		return err
+	}
+
+	tradeLedger, err := ledger.Open(ledgerPath(*stateDir))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Scanning transactions of %s for %d pairs...\n", owner, len(pairs))
+	result, err := tradeLedger.Sync(ctx, client, owner, pairs, opts)
+	fmt.Printf("Scanned %d transactions: %d swaps added, %d already recorded, %d not pair swaps, %d failed or unreadable\n",
+		result.Scanned, result.Added, result.Recorded, result.NotSwaps, result.Failed)
+	if result.LastError != nil {
+		fmt.Printf("Last error: %v\n", result.LastError)
+	}
+	return err
+}
+
+// walletAddress parses a wallet address, falling back to the public key of PRIVATE_KEY
+func walletAddress(address string) (solana.PublicKey, error) {
+	if address != "" {
+		owner, err := solana.PublicKeyFromBase58(address)
+		if err != nil {
+			return owner, fmt.Errorf("invalid wallet address %q: %v", address, err)
+		}
+		return owner, nil
+	}
+	privateKey, err := solana.PrivateKeyFromBase58(utils.GetEnv("PRIVATE_KEY", ""))
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("set -wallet or a valid PRIVATE_KEY: %v", err)
+	}
+	return privateKey.PublicKey(), nil
+}
+
+// syncPairs resolves the configured pairs (RISK_ASSET/QUOTE_ASSETS or PAIRS_FILE) for a history sync
+func syncPairs(ctx context.Context, registry *token.Registry) ([]ledger.SyncPair, error) {
+	pairs := []datatypes.PairConfig{{
+		RiskAsset:   utils.GetEnv("RISK_ASSET", "SOL"),
+		QuoteAssets: strings.Split(utils.GetEnv("QUOTE_ASSETS", "USDC"), ","),
+	}}
+	if pairsFile := utils.GetEnv("PAIRS_FILE", ""); pairsFile != "" {
+		pairs = nil
+		if err := utils.ReadJSONFile(pairsFile, &pairs); err != nil {
+			return nil, fmt.Errorf("failed to load pairs from %s: %v", pairsFile, err)
+		}
+	}
+
+	var resolved []ledger.SyncPair
+	for _, pair := range pairs {
+		quoteAssets := pair.QuoteAssets
+		if len(quoteAssets) == 0 {
+			quoteAssets = strings.Split(utils.GetEnv("QUOTE_ASSETS", "USDC"), ",")
+		}
+
+		risk, err := registry.Resolve(ctx, pair.RiskAsset)
+		if err != nil {
+			return nil, err
+		}
+		syncPair := ledger.SyncPair{
+			RiskMint:   risk.Mint.String(),
+			RiskSymbol: risk.Symbol,
+			Quotes:     make(map[string]string),
+		}
+		for i, asset := range quoteAssets {
+			quote, err := registry.Resolve(ctx, strings.TrimSpace(asset))
+			if err != nil {
+				return nil, err
+			}
+			if i == 0 {
+				syncPair.Name = risk.Symbol + "/" + quote.Symbol
+			}
+			syncPair.Quotes[quote.Mint.String()] = quote.Symbol
+		}
+		resolved = append(resolved, syncPair)
+	}
+	return resolved, nil
+}
+
+// parseSince parses an absolute RFC3339 time or a duration before now. Empty means no bound.
+func parseSince(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: use RFC3339 or a duration such as 24h", value)
+	}
+	return time.Now().Add(-duration), nil
+}
+
+// ledgerPath returns the location of the trade ledger within the state directory
+func ledgerPath(stateDir string) string {
+	return filepath.Join(stateDir, "ledger.jsonl")
+}
+
+// runReport handles the `report` subcommands
+func runReport(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: report <pnl|tax> [flags]")
+	}
+
+	switch args[0] {
+	case "pnl":
+		return reportPnL(args[1:])
+	case "tax":
+		return reportTax(args[1:])
+	default:
+		return fmt.Errorf("unknown report %q (available: pnl, tax)", args[0])
+	}
+}
+
+// reportPnL prints realized and unrealized PnL per pair and compares the bot against buying and holding
+func reportPnL(args []string) error {
+	flags := flag.NewFlagSet("report pnl", flag.ContinueOnError)
+	stateDir := flags.String("state", utils.GetEnv("STATE_DIR", "state"), "state directory containing the ledger")
+	method := flags.String("method", ledger.MethodFIFO, "cost basis method: fifo or average")
+	pairFilter := flags.String("pair", "", "only report this pair, e.g. SOL/USDC")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	tradeLedger, err := ledger.Open(ledgerPath(*stateDir))
+	if err != nil {
+		return err
+	}
+	benchmarks, err := tradeLedger.Benchmarks()
+	if err != nil {
+		return err
+	}
+
+	pairs := make(map[string]bool)
+	for pair := range benchmarks {
+		pairs[pair] = true
+	}
+	for _, trade := range tradeLedger.Trades() {
+		pairs[trade.Pair] = true
+	}
+	names := make([]string, 0, len(pairs))
+	for pair := range pairs {
+		if *pairFilter == "" || strings.EqualFold(pair, *pairFilter) {
+			names = append(names, pair)
+		}
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		fmt.Println("No trades or benchmarks recorded yet")
+		return nil
+	}
+
+	for _, pair := range names {
+		benchmark, hasBenchmark := benchmarks[pair]
+		var opening *ledger.Lot
+		if hasBenchmark {
+			opening = benchmark.OpeningLot()
+		}
+
+		summary, err := ledger.ComputeCostBasis(tradeLedger.PairTrades(pair), *method, opening, nil)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s\n", pair)
+		fmt.Printf("  Trades:            %d (fees %.4f)\n", summary.Trades, summary.FeesQuote)
+		fmt.Printf("  Realized PnL:      %.4f (%s)\n", summary.RealizedPnL, summary.Method)
+		fmt.Printf("  Open position:     %.6g at average cost %.6g\n", summary.OpenQuantity, summary.AverageCost())
+
+		if !hasBenchmark {
+			fmt.Println()
+			continue
+		}
+
+		unrealized := summary.OpenQuantity*benchmark.LastPrice - summary.OpenCost
+		hold := benchmark.HoldValue(benchmark.LastPrice)
+		fmt.Printf("  Unrealized PnL:    %.4f at %.6g\n", unrealized, benchmark.LastPrice)
+		fmt.Printf("  Started:           %s with equity %.4f at %.6g\n",
+			benchmark.StartTime.Format(time.RFC3339), benchmark.StartEquity, benchmark.StartPrice)
+		fmt.Printf("  Bot equity:        %.4f (as of %s)\n", benchmark.LastEquity, benchmark.LastTime.Format(time.RFC3339))
+		fmt.Printf("  Buy and hold:      %.4f\n", hold)
+
+		verdict := "beat"
+		if benchmark.LastEquity < hold {
+			verdict = "trailed"
+		}
+		fmt.Printf("  Cycling %s holding by %.4f\n\n", verdict, benchmark.LastEquity-hold)
+	}
+	return nil
+}
+
+// reportTax exports executed swaps with the realized gain of every lot sold as CSV
+func reportTax(args []string) error {
+	flags := flag.NewFlagSet("report tax", flag.ContinueOnError)
+	stateDir := flags.String("state", utils.GetEnv("STATE_DIR", "state"), "state directory containing the ledger")
+	method := flags.String("method", ledger.MethodFIFO, "lot matching: fifo or specific-id")
+	lotsFile := flags.String("lots", "", "JSON file mapping sell signatures to the buy signatures of the lots sold (specific-id)")
+	openingFile := flags.String("opening", "", "JSON file mapping pairs to the cost per unit and acquisition date of the starting position")
+	fromDate := flags.String("from", "", "first day to export, YYYY-MM-DD (default: all)")
+	toDate := flags.String("to", "", "last day to export, YYYY-MM-DD (default: all)")
+	timezone := flags.String("tz", "UTC", "timezone for the date range and exported dates, e.g. Europe/Berlin")
+	out := flags.String("out", "", "output file (default: stdout)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *method != ledger.MethodFIFO && *method != ledger.MethodSpecificID {
+		return fmt.Errorf("unsupported lot matching method %q: use fifo or specific-id", *method)
+	}
+
+	loc, err := time.LoadLocation(*timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q: %v", *timezone, err)
+	}
+	from, to, err := parseDateRange(*fromDate, *toDate, loc)
+	if err != nil {
+		return err
+	}
+
+	var selections map[string][]string
+	if *lotsFile != "" {
+		if err := utils.ReadJSONFile(*lotsFile, &selections); err != nil {
+			return fmt.Errorf("failed to read lot selections: %v", err)
+		}
+	}
+
+	var openings map[string]openingBasis
+	if *openingFile != "" {
+		if err := utils.ReadJSONFile(*openingFile, &openings); err != nil {
+			return fmt.Errorf("failed to read opening bases: %v", err)
+		}
+	}
+
+	tradeLedger, err := ledger.Open(ledgerPath(*stateDir))
+	if err != nil {
+		return err
+	}
+	benchmarks, err := tradeLedger.Benchmarks()
+	if err != nil {
+		return err
+	}
+
+	pairs := make(map[string]bool)
+	for _, trade := range tradeLedger.Trades() {
+		pairs[trade.Pair] = true
+	}
+	names := make([]string, 0, len(pairs))
+	for pair := range pairs {
+		names = append(names, pair)
+	}
+	sort.Strings(names)
+
+	var rows []ledger.TaxRow
+	for _, pair := range names {
+		var opening *ledger.Lot
+		if benchmark, ok := benchmarks[pair]; ok {
+			opening = benchmark.OpeningLot()
+			if basis, ok := openings[pair]; ok {
+				if opening.Acquired, err = time.ParseInLocation("2006-01-02", basis.Acquired, loc); err != nil {
+					return fmt.Errorf("invalid opening acquisition date for %s %q: %v", pair, basis.Acquired, err)
+				}
+				opening.CostPerUnit = basis.CostPerUnit
+				opening.Basis = ledger.BasisUser
+			}
+		}
+
+		trades := tradeLedger.PairTrades(pair)
+		summary, err := ledger.ComputeCostBasis(trades, *method, opening, selections)
+		if err != nil {
+			return err
+		}
+		rows = append(rows, ledger.TaxRows(trades, summary, from, to)...)
+	}
+	ledger.SortTaxRows(rows)
+
+	estimated := 0
+	for _, row := range rows {
+		if row.Estimated() {
+			estimated++
+		}
+	}
+	if estimated > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %d rows have an estimated cost basis or acquisition date (basis_source %s or %s); "+
+			"supply the starting position's basis with -opening\n", estimated, ledger.BasisMarketAtStart, ledger.BasisUnknown)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	return ledger.WriteTaxCSV(w, rows, loc)
+}
+
+// openingBasis is the user-supplied cost of the position a pair started with
+type openingBasis struct {
+	CostPerUnit float64 `json:"costPerUnit"`
+	Acquired    string  `json:"acquired"` // YYYY-MM-DD
+}
+
+// parseDateRange parses an inclusive range of YYYY-MM-DD days in loc into [from, to) times.
+// Empty bounds are left open.
+func parseDateRange(fromDate, toDate string, loc *time.Location) (time.Time, time.Time, error) {
+	var from, to time.Time
+	var err error
+	if fromDate != "" {
+		if from, err = time.ParseInLocation("2006-01-02", fromDate, loc); err != nil {
+			return from, to, fmt.Errorf("invalid -from date %q: %v", fromDate, err)
+		}
+	}
+	if toDate != "" {
+		if to, err = time.ParseInLocation("2006-01-02", toDate, loc); err != nil {
+			return from, to, fmt.Errorf("invalid -to date %q: %v", toDate, err)
+		}
+		to = to.AddDate(0, 0, 1)
+	}
+	return from, to, nil
+}
+
+// runLogs queries, summarizes or follows the swap audit log
+func runLogs(args []string) error {
+	flags := flag.NewFlagSet("logs", flag.ContinueOnError)
+	file := flags.String("file", filepath.Join("logs", "swaps.jsonl"), "swap audit log")
+	status := flags.String("status", "", "only swaps with this status: ATTEMPT, SENT, SUCCESS or FAILED")
+	since := flags.String("since", "", "only swaps from this time (RFC3339) or this long ago (e.g. 24h)")
+	until := flags.String("until", "", "only swaps before this time (RFC3339) or this long ago")
+	mint := flags.String("mint", "", "only swaps from or to this token symbol or mint")
+	signature := flags.String("signature", "", "only the swap with this transaction signature")
+	swapID := flags.String("swap", "", "only the swap with this ID")
+	records := flags.Bool("records", false, "print every status record instead of one line per swap")
+	summary := flags.Bool("summary", false, "print success rate, failure classes and averages instead of swaps")
+	follow := flags.Bool("follow", false, "keep printing new records as they are written, like tail -f")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	filter := logger.SwapFilter{
+		Status:    *status,
+		Signature: *signature,
+		SwapID:    *swapID,
+	}
+	if *mint != "" {
+		filter.Mint = token.MintAddress(*mint)
+	}
+	var err error
+	if filter.From, err = parseSince(*since); err != nil {
+		return err
+	}
+	if filter.To, err = parseSince(*until); err != nil {
+		return err
+	}
+
+	if *follow {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		fmt.Fprintf(os.Stderr, "Following %s (Ctrl+C to stop)\n", *file)
+		err := logger.FollowSwapRecords(ctx, *file, func(record logger.SwapRecord) {
+			if filter.MatchRecord(record) {
+				printSwapRecord(record)
+			}
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+		return err
+	}
+
+	all, err := logger.ReadSwapRecords(*file)
+	if err != nil {
+		return err
+	}
+
+	if *records {
+		for _, record := range all {
+			if filter.MatchRecord(record) {
+				printSwapRecord(record)
+			}
+		}
+		return nil
+	}
+
+	var swaps []logger.SwapSummary
+	for _, swap := range logger.SummarizeSwaps(all) {
+		if filter.MatchSwap(swap) {
+			swaps = append(swaps, swap)
+		}
+	}
+
+	if *summary {
+		printSwapStats(logger.ComputeSwapStats(swaps))
+		return nil
+	}
+	for _, swap := range swaps {
+		printSwapRecord(logger.SwapRecord{
+			Time:        swap.Started,
+			SwapID:      swap.SwapID,
+			Status:      swap.Status,
+			InputMint:   swap.InputMint,
+			OutputMint:  swap.OutputMint,
+			Amount:      swap.Amount,
+			SlippageBps: swap.SlippageBps,
+			Signature:   swap.Signature,
+			Error:       swap.Error,
+		})
+	}
+	return nil
+}
+
+// printSwapRecord prints a swap or one of its audit records on a line
+func printSwapRecord(record logger.SwapRecord) {
+	line := fmt.Sprintf("%s  %s  %-7s %s -> %s  amount %d  slippage %dbps",
+		record.Time.Local().Format("2006-01-02 15:04:05"), record.SwapID, record.Status,
+		token.Symbol(record.InputMint), token.Symbol(record.OutputMint), record.Amount, record.SlippageBps)
+	if record.Signature != "" {
+		line += "  tx " + record.Signature
+	}
+	if record.Error != "" {
+		line += "  error: " + record.Error
+	}
+	fmt.Println(line)
+}
+
+// printSwapStats prints the outcome summary of a set of swaps
+func printSwapStats(stats logger.SwapStats) {
+	fmt.Printf("Swaps:            %d (%d succeeded, %d failed, %d pending)\n",
+		stats.Total, stats.Succeeded, stats.Failed, stats.Pending)
+	fmt.Printf("Success rate:     %.1f%%\n", stats.SuccessRate()*100)
+	fmt.Printf("Avg slippage:     %.0f bps\n", stats.AverageSlippageBps)
+	fmt.Printf("Avg duration:     %s\n", stats.AverageDuration.Round(time.Second))
+	if stats.Failed > 0 {
+		fmt.Println("Failures by class:")
+		for _, class := range stats.FailureClassesByCount() {
+			fmt.Printf("  %-20s %d\n", class, stats.FailureClasses[class])
+		}
+	}
+}
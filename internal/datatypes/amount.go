@@ -0,0 +1,57 @@
+package datatypes
+
+import (
+	"fmt"
+	"math"
+)
+
+// TokenAmount is a token balance in integer base units together with the mint's decimals
+type TokenAmount struct {
+	Raw      uint64
+	Decimals uint8
+}
+
+// NewTokenAmount converts a UI amount (e.g. 1.5 SOL) into base units for the given decimals
+func NewTokenAmount(value float64, decimals uint8) TokenAmount {
+	if value <= 0 {
+		return TokenAmount{Decimals: decimals}
+	}
+	return TokenAmount{
+		Raw:      uint64(math.Floor(value * math.Pow10(int(decimals)))),
+		Decimals: decimals,
+	}
+}
+
+// Float returns the amount in UI units
+func (a TokenAmount) Float() float64 {
+	return float64(a.Raw) / math.Pow10(int(a.Decimals))
+}
+
+// IsZero reports whether the amount is empty
+func (a TokenAmount) IsZero() bool {
+	return a.Raw == 0
+}
+
+// Sub returns the amount minus other, floored at zero
+func (a TokenAmount) Sub(other TokenAmount) TokenAmount {
+	if other.Raw >= a.Raw {
+		return TokenAmount{Decimals: a.Decimals}
+	}
+	return TokenAmount{Raw: a.Raw - other.Raw, Decimals: a.Decimals}
+}
+
+// Fraction returns the given fraction (0, 1] of the amount, rounded down
+func (a TokenAmount) Fraction(fraction float64) TokenAmount {
+	if fraction >= 1 {
+		return a
+	}
+	if fraction <= 0 {
+		return TokenAmount{Decimals: a.Decimals}
+	}
+	return TokenAmount{Raw: uint64(float64(a.Raw) * fraction), Decimals: a.Decimals}
+}
+
+// String formats the amount in UI units with full precision
+func (a TokenAmount) String() string {
+	return fmt.Sprintf("%.*f", a.Decimals, a.Float())
+}
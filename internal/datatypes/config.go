@@ -6,8 +6,8 @@ import "github.com/gagliardetto/solana-go"
 type Config struct {
 	PrivateKey    string
 	PublicKey     solana.PublicKey
-	StopLossPrice float64
-	MinimumSOL    float64
+	StopLossPrice float64 // Price of the risk asset in quote units
+	MinimumSOL    float64 // User floor for the SOL reserve; the computed reserve never goes below it
 	RPCEndpoint   string
 	USDCMint      string
 	PriceAPIURL   string
@@ -20,4 +20,132 @@ type Config struct {
 	DynamicStopLoss    bool    // Whether to use dynamic stop loss
 	StopLossAdjustment float64 // Amount to keep the stop loss below highest price (e.g., 4.0-10.0)
 	HighestPrice       float64 // Track the highest price seen for dynamic stop loss
+
+	// Volatility-adaptive stop distance. When enabled, StopLossAdjustment is only used
+	// until enough candles have closed.
+	VolatilityStop         bool    // Derive the stop distance from recent realized volatility
+	VolatilityMethod       string  // "atr" (average true range of the candles) or "stddev" (of their closes)
+	VolatilityCandlePeriod int     // Length in seconds of the candles volatility is measured over (e.g. 300)
+	VolatilityWindow       int     // Number of candles to measure volatility over (e.g. 14)
+	VolatilityMultiplier   float64 // Stop distance as a multiple of the measured volatility
+	MinStopPct             float64 // Lower clamp for the adaptive stop distance in percent of the price
+	MaxStopPct             float64 // Upper clamp for the adaptive stop distance in percent of the price
+
+	// Fee-aware trade filtering
+	FeeCheckEnabled     bool    // Whether to estimate round-trip costs before swapping
+	MinTradeValue       float64 // Skip swaps whose notional value is below this many quote units
+	MinBenefitCostRatio float64 // Required ratio of expected protection to estimated round-trip cost
+
+	// Trade frequency limits
+	SwapCooldown    int // Seconds to wait after a swap before the next one
+	MaxSwapsPerHour int // Maximum swaps in any rolling hour (0 for no limit)
+	MaxSwapsPerDay  int // Maximum swaps in any rolling 24 hours (0 for no limit)
+	MinHoldTime     int // Seconds to hold the risk asset after buying before it may be sold
+
+	// Risk circuit breaker
+	DailyLossLimit float64 // Halt trading after losing this many USD since the start of the UTC day (0 disables)
+	MaxDrawdownPct float64 // Halt trading when equity falls this many percent below its peak (0 disables)
+	KillSwitchFile string  // Halt trading while this file exists
+	EquityInterval int     // Seconds between equity valuations for the loss limits and benchmark; swaps trigger one too
+
+	// SOL reserve configuration
+	DynamicReserve bool // Derive the SOL reserve from rent and fee estimates instead of using MinimumSOL alone
+	ReserveTxCount int  // Number of future transactions the reserve must be able to pay for
+
+	// Token account configuration
+	AutoCreateTokenAccounts bool // Create missing token accounts at startup without asking for confirmation
+
+	// Token pair configuration
+	RiskAsset      string   // Symbol or mint of the asset being cycled (e.g. SOL, JUP, BONK, mSOL)
+	QuoteAssets    []string // Quote symbols (USDC, USDT, PYUSD) or mint addresses; the first is the default
+	BestQuoteAsset bool     // Sell into whichever configured quote asset gives the best Jupiter quote
+
+	// Target-allocation rebalancing
+	RebalanceEnabled   bool    // Rebalance towards target weights instead of switching all-in/all-out
+	TargetRiskWeight   float64 // Target share of the portfolio value held in the risk asset (0-1)
+	StopRiskWeight     float64 // Risk asset weight to move to while the stop loss is triggered (e.g. 0)
+	RebalanceDriftBand float64 // Only rebalance once the risk weight drifts further than this from target (e.g. 0.05)
+	RebalanceMinTrade  float64 // Skip rebalancing trades worth less than this in quote units
+
+	// Indicator entry filters over candles built from the polled prices
+	EntryFilters    []string // Conditions that must all hold before buying back: "ema_cross", "rsi", "bollinger"
+	CandlePeriod    int      // Candle length in seconds
+	FastEMAPeriod   int      // Fast EMA period in candles for "ema_cross" (e.g. 20)
+	SlowEMAPeriod   int      // Slow EMA period in candles for "ema_cross" (e.g. 50)
+	RSIPeriod       int      // RSI period in candles for "rsi" (e.g. 14)
+	RSIMaxEntry     float64  // Only buy back while the RSI is below this (e.g. 70)
+	BollingerPeriod int      // Bollinger band period in candles for "bollinger" (e.g. 20)
+	BollingerStdDev float64  // Bollinger band width in standard deviations (e.g. 2)
+
+	// Take-profit rules
+	TakeProfitLevels   []TakeProfitLevel // Levels at which to sell into strength, in any order
+	TakeProfitReentry  string            // Re-entry after a full take profit: "pullback" (default), "breakout" or "never"
+	ReentryPullbackPct float64           // Percent below the take-profit exit price to buy back at in pullback mode
+
+	// Buy-back ladder. Instead of re-entering in full, buy back in tranches.
+	LadderEnabled     bool            // Buy back in tranches rather than all at once
+	LadderTranches    []LadderTranche // Price-triggered tranches, used when LadderTWAPSlices is zero
+	LadderTWAPSlices  int             // Split the buy-back into this many equal time slices (0 disables TWAP)
+	LadderTWAPMinutes int             // Period the TWAP slices are spread over
+
+	// Sliced execution for large swaps
+	MaxSliceValue     float64 // Split swaps worth more than this (in quote units) into child swaps; 0 disables slicing
+	SliceDelay        int     // Seconds to wait between child swaps
+	MaxPriceImpactPct float64 // Abort remaining slices when a slice's quoted price impact exceeds this percentage
+	MaxPriceMovePct   float64 // Abort remaining slices when the price moves this many percent against the first slice
+
+	// State persistence
+	StateDir string // Directory for persisted strategy state
+
+	// Multi-pair configuration. When empty, a single strategy runs on RiskAsset/QuoteAssets.
+	Pairs []PairConfig
+}
+
+// TakeProfitLevel sells part or all of the risk asset once the price reaches a level
+type TakeProfitLevel struct {
+	Price             float64 `json:"price,omitempty"`             // Absolute trigger price in quote units
+	PercentAboveEntry float64 `json:"percentAboveEntry,omitempty"` // Trigger relative to the entry price, used when Price is zero
+	Fraction          float64 `json:"fraction"`                    // Share of the spendable position to sell (0-1], 1 exits fully
+}
+
+// LadderTranche is one step of the buy-back ladder
+type LadderTranche struct {
+	Fraction       float64 `json:"fraction"`       // Share of the quote position to spend (0-1)
+	PriceOffsetPct float64 `json:"priceOffsetPct"` // Percent above the stop loss the price must reach
+}
+
+// PairConfig overrides the base configuration for one pair strategy.
+// Zero values inherit the base configuration.
+type PairConfig struct {
+	RiskAsset          string   `json:"riskAsset"`
+	QuoteAssets        []string `json:"quoteAssets,omitempty"`
+	StopLossPrice      float64  `json:"stopLossPrice,omitempty"`
+	StopLossAdjustment float64  `json:"stopLossAdjustment,omitempty"`
+	CheckInterval      int      `json:"checkInterval,omitempty"`
+}
+
+// ForPair returns a copy of the configuration with the pair's overrides applied.
+// Each pair gets its own copy so per-pair state such as HighestPrice is not shared.
+func (c *Config) ForPair(pair PairConfig) *Config {
+	cfg := *c
+	cfg.Pairs = nil
+	cfg.HighestPrice = 0
+
+	if pair.RiskAsset != "" {
+		cfg.RiskAsset = pair.RiskAsset
+	}
+	if len(pair.QuoteAssets) > 0 {
+		cfg.QuoteAssets = pair.QuoteAssets
+	}
+	if pair.StopLossPrice > 0 {
+		cfg.StopLossPrice = pair.StopLossPrice
+	}
+	if pair.StopLossAdjustment > 0 {
+		cfg.StopLossAdjustment = pair.StopLossAdjustment
+	}
+	if pair.CheckInterval > 0 {
+		cfg.CheckInterval = pair.CheckInterval
+	}
+
+	return &cfg
 }
@@ -1,8 +1,10 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 )
 
 // Helper function to parse string to float64
@@ -20,3 +22,38 @@ func GetEnv(key, defaultValue string) string {
 	}
 	return value
 }
+
+// Helper function to get a numeric environment variable with a default value
+func GetEnvFloat(key string, defaultValue float64) float64 {
+	value, err := ParseFloat(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// Helper function to read a JSON file into the given value
+func ReadJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Helper function to atomically write a value as JSON, creating parent directories as needed
+func WriteJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -3,13 +3,26 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
 	"path/filepath"
+	"strings"
 	"swap/internal/datatypes"
 	"swap/internal/utils"
 	"swap/pkg/logger"
+	"swap/pkg/metrics"
+	"swap/service/health"
+	"swap/service/history"
 	"swap/service/jupiter"
+	"swap/service/ledger"
+	"swap/service/risk"
 	"swap/service/swap"
+	"swap/service/token"
+	"syscall"
+	"time"
 
 	"github.com/gagliardetto/solana-go/rpc"
 	jupClient "github.com/ilkamo/jupiter-go/jupiter"
@@ -21,37 +34,57 @@ import (
 )
 
 func main() {
+	// Subcommands such as `history export` run without starting the bot
+	if len(os.Args) > 1 {
+		if err := godotenv.Load(); err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(os.Stderr, "Error loading .env file: %v\n", err)
+		}
+		if err := runCommand(os.Args[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Load environment variables from .env file
+	envErr := godotenv.Load()
+
 	// Initialize the logger
 	activityLogPath := filepath.Join("logs", "activity.txt")
-	if err := logger.Init(activityLogPath); err != nil {
+	if err := logger.InitWithOptions(activityLogPath, logOptions()); err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
 	defer logger.Close()
 
-	// Initialize the swap logger
-	swapLogPath := filepath.Join("logs", "swap.txt")
-	if err := logger.InitSwapLogger(swapLogPath); err != nil {
-		logger.Error("Failed to initialize swap logger: %v", err)
+	// Record every swap attempt and its outcome in the audit log
+	swapLogPath := filepath.Join("logs", "swaps.jsonl")
+	if err := logger.InitAuditLog(swapLogPath, logOptions()); err != nil {
+		logger.Error("Failed to initialize swap audit log: %v", err)
 	}
-	defer logger.CloseSwapLogger()
+	defer logger.CloseAuditLog()
 
 	logger.Info("Starting swap script")
 
-	// Load environment variables from .env file
-	if err := godotenv.Load(); err != nil {
-		logger.Warn("Error loading .env file: %v", err)
+	if envErr != nil {
+		logger.Warn("Error loading .env file: %v", envErr)
 	}
 
 	// Get private key and RPC endpoint from environment variables
 	privateKeyStr := utils.GetEnv("PRIVATE_KEY", "")
 	rpcEndpoint := utils.GetEnv("RPC_ENDPOINT", "https://api.mainnet-beta.solana.com")
 
+	// A mistyped take-profit level would otherwise never fire
+	tpLevels, err := takeProfitLevels(utils.GetEnv("TAKE_PROFIT_LEVELS", ""))
+	if err != nil {
+		log.Fatalf("Invalid TAKE_PROFIT_LEVELS: %v", err)
+	}
+
 	// We need to create a config object manually since we don't have the config package yet
 	cfg := &datatypes.Config{
 		PrivateKey:    privateKeyStr,
 		RPCEndpoint:   rpcEndpoint,
-		StopLossPrice: 130.0,
-		MinimumSOL:    0.1,
+		StopLossPrice: utils.GetEnvFloat("STOP_LOSS_PRICE", 130.0),
+		MinimumSOL:    0.1, // Floor for the SOL reserve
 		CheckInterval: 2,
 
 		// Retry configuration
@@ -61,8 +94,104 @@ func main() {
 
 		// Dynamic stop loss configuration
 		DynamicStopLoss:    true,
-		StopLossAdjustment: 5.0, // Keep stop loss $5 below the highest price
-		HighestPrice:       0.0, // Initialize highest price to 0
+		StopLossAdjustment: utils.GetEnvFloat("STOP_LOSS_ADJUSTMENT", 5.0), // Keep stop loss $5 below the highest price
+		HighestPrice:       0.0,                                            // Initialize highest price to 0
+
+		// Volatility-adaptive stop distance: 3x the average true range of 14 five-minute candles,
+		// never tighter than 0.5% or looser than 10% of the price
+		VolatilityStop:         utils.GetEnv("VOLATILITY_STOP", "false") == "true",
+		VolatilityMethod:       utils.GetEnv("VOLATILITY_METHOD", "atr"),
+		VolatilityCandlePeriod: int(utils.GetEnvFloat("VOLATILITY_CANDLE_PERIOD", 300)),
+		VolatilityWindow:       int(utils.GetEnvFloat("VOLATILITY_WINDOW", 14)),
+		VolatilityMultiplier:   utils.GetEnvFloat("VOLATILITY_MULTIPLIER", 3.0),
+		MinStopPct:             utils.GetEnvFloat("MIN_STOP_PCT", 0.5),
+		MaxStopPct:             utils.GetEnvFloat("MAX_STOP_PCT", 10),
+
+		// Fee-aware trade filtering
+		FeeCheckEnabled:     true,
+		MinTradeValue:       5.0, // Don't bother swapping positions worth less than 5 quote units
+		MinBenefitCostRatio: 1.0, // Expected protection must at least cover round-trip costs
+
+		// Trade frequency limits to keep fee drag down
+		SwapCooldown:    int(utils.GetEnvFloat("SWAP_COOLDOWN", 60)), // At least a minute between swaps
+		MaxSwapsPerHour: int(utils.GetEnvFloat("MAX_SWAPS_PER_HOUR", 6)),
+		MaxSwapsPerDay:  int(utils.GetEnvFloat("MAX_SWAPS_PER_DAY", 0)),
+		MinHoldTime:     int(utils.GetEnvFloat("MIN_HOLD_TIME", 0)),
+
+		// Risk circuit breaker. Touch the kill switch file (or send SIGUSR1) to halt trading;
+		// remove it (or send SIGUSR2) to resume.
+		DailyLossLimit: utils.GetEnvFloat("DAILY_LOSS_LIMIT", 0),
+		MaxDrawdownPct: utils.GetEnvFloat("MAX_DRAWDOWN_PCT", 0),
+		KillSwitchFile: utils.GetEnv("KILL_SWITCH_FILE", "KILL"),
+		EquityInterval: int(utils.GetEnvFloat("EQUITY_INTERVAL", 60)),
+
+		// SOL reserve configuration
+		DynamicReserve: true,
+		ReserveTxCount: 4, // Enough for the buy-back plus a few retries
+
+		// Create missing token accounts without prompting
+		AutoCreateTokenAccounts: utils.GetEnv("AUTO_CREATE_TOKEN_ACCOUNTS", "false") == "true",
+
+		// Target-allocation rebalancing, e.g. 60% risk asset / 40% quote asset
+		RebalanceEnabled:   utils.GetEnv("REBALANCE", "false") == "true",
+		TargetRiskWeight:   utils.GetEnvFloat("TARGET_RISK_WEIGHT", 0.6),
+		StopRiskWeight:     utils.GetEnvFloat("STOP_RISK_WEIGHT", 0.0),
+		RebalanceDriftBand: utils.GetEnvFloat("REBALANCE_DRIFT_BAND", 0.05),
+		RebalanceMinTrade:  10.0, // Don't rebalance for less than 10 quote units
+
+		// Indicator filters gating buy-backs, e.g. ENTRY_FILTERS=ema_cross,rsi
+		EntryFilters:    splitList(utils.GetEnv("ENTRY_FILTERS", "")),
+		CandlePeriod:    60, // 1 minute candles
+		FastEMAPeriod:   20,
+		SlowEMAPeriod:   50,
+		RSIPeriod:       14,
+		RSIMaxEntry:     70,
+		BollingerPeriod: 20,
+		BollingerStdDev: 2,
+
+		// Take-profit levels as percent above entry and share to sell, e.g. "10:0.5,20:1"
+		TakeProfitLevels:   tpLevels,
+		TakeProfitReentry:  utils.GetEnv("TAKE_PROFIT_REENTRY", "pullback"),
+		ReentryPullbackPct: 3.0, // Buy back 3% below the take-profit exit
+
+		// Buy-back ladder: 25% at the stop, then 25% at each further 2% rise.
+		// Set LADDER_TWAP_SLICES to spread equal slices over LADDER_TWAP_MINUTES instead.
+		LadderEnabled: utils.GetEnv("LADDER", "false") == "true",
+		LadderTranches: []datatypes.LadderTranche{
+			{Fraction: 0.25, PriceOffsetPct: 0},
+			{Fraction: 0.25, PriceOffsetPct: 2},
+			{Fraction: 0.25, PriceOffsetPct: 4},
+			{Fraction: 0.25, PriceOffsetPct: 6},
+		},
+		LadderTWAPSlices:  int(utils.GetEnvFloat("LADDER_TWAP_SLICES", 0)),
+		LadderTWAPMinutes: int(utils.GetEnvFloat("LADDER_TWAP_MINUTES", 60)),
+
+		// Split swaps worth more than MAX_SLICE_VALUE into child swaps (0 disables slicing)
+		MaxSliceValue:     utils.GetEnvFloat("MAX_SLICE_VALUE", 0),
+		SliceDelay:        30,  // Seconds between child swaps
+		MaxPriceImpactPct: 1.0, // Abort when a slice would move the market more than 1%
+		MaxPriceMovePct:   2.0, // Abort when the price falls 2% below the first slice
+
+		// Persisted strategy state
+		StateDir: utils.GetEnv("STATE_DIR", "state"),
+
+		// Token pair configuration
+		RiskAsset:      utils.GetEnv("RISK_ASSET", "SOL"),
+		QuoteAssets:    strings.Split(utils.GetEnv("QUOTE_ASSETS", "USDC"), ","),
+		BestQuoteAsset: utils.GetEnv("BEST_QUOTE_ASSET", "false") == "true",
+	}
+
+	// Optionally run several pairs side by side, e.g. PAIRS_FILE=pairs.json
+	if pairsFile := utils.GetEnv("PAIRS_FILE", ""); pairsFile != "" {
+		if err := utils.ReadJSONFile(pairsFile, &cfg.Pairs); err != nil {
+			log.Fatalf("Failed to load pairs from %s: %v", pairsFile, err)
+		}
+		logger.Info("Loaded %d pairs from %s", len(cfg.Pairs), pairsFile)
+	}
+
+	// The default stop loss is a SOL price, so other risk assets need their own
+	if err := checkStopLoss(cfg, os.Getenv("STOP_LOSS_PRICE") != "", os.Getenv("STOP_LOSS_ADJUSTMENT") != ""); err != nil {
+		log.Fatalf("Invalid stop loss configuration: %v", err)
 	}
 
 	// Derive public key from private key
@@ -82,9 +211,86 @@ func main() {
 	// Initialize services
 	solService := solanaService.NewService(client)
 	jupiterSvc := jupiter.NewService(jupClient, cfg)
+	tokenSvc := token.NewService(client, privateKey, cfg.AutoCreateTokenAccounts)
+	registry := token.NewRegistry(client)
+
+	// Create missing token accounts up front and reclaim any leftover wrapped SOL
+	if _, err := tokenSvc.EnsureAccounts(context.Background(), pairMints(cfg)...); err != nil {
+		logger.Error("Failed to prepare token accounts: %v", err)
+		log.Fatalf("Failed to prepare token accounts: %v", err)
+	}
+	if err := tokenSvc.CloseWrappedSOL(context.Background()); err != nil {
+		logger.Warn("Failed to reclaim wrapped SOL: %v", err)
+	}
+
+	// Record every polled price and its candles for later analysis
+	store, err := history.Open(utils.GetEnv("HISTORY_DIR", "history"), history.Config{
+		SampleRetention: time.Duration(utils.GetEnvFloat("HISTORY_SAMPLE_DAYS", 7) * 24 * float64(time.Hour)),
+	})
+	if err != nil {
+		logger.Warn("Price history disabled: %v", err)
+		store = nil
+	} else {
+		defer store.Close()
+	}
+
+	// Halt trading on loss limits or the kill switch
+	riskManager := risk.NewManager(risk.Limits{
+		DailyLossLimit: cfg.DailyLossLimit,
+		MaxDrawdownPct: cfg.MaxDrawdownPct,
+		KillSwitchFile: cfg.KillSwitchFile,
+		StateFile:      filepath.Join(cfg.StateDir, "risk.json"),
+	})
+	go handleKillSignals(riskManager)
 
-	// Create swap service
-	swapService, err := swap.NewService(cfg, client, solService, jupiterSvc)
+	// Record executed trades with their actual amounts
+	tradeLedger, err := ledger.Open(ledgerPath(cfg.StateDir))
+	if err != nil {
+		logger.Warn("Trade ledger disabled: %v", err)
+		tradeLedger = nil
+	}
+
+	// Track completed cycles for the health checks and the watchdog
+	healthMonitor := health.NewMonitor(health.Config{
+		StallIntervals: int(utils.GetEnvFloat("WATCHDOG_INTERVALS", 5)),
+		SwapGrace:      time.Duration(utils.GetEnvFloat("SWAP_GRACE", 900)) * time.Second,
+		Probe: func(ctx context.Context) error {
+			_, err := client.GetHealth(ctx)
+			return err
+		},
+	})
+	watchdogExit := utils.GetEnv("WATCHDOG_EXIT", "false") == "true"
+	go healthMonitor.Watch(context.Background(), func(status health.PairStatus) {
+		if watchdogExit {
+			// Exit non-zero so a supervisor such as systemd or Docker restarts the bot
+			logger.Error("Exiting: %s strategy stalled", status.Pair)
+			logger.CloseAuditLog()
+			logger.Close()
+			os.Exit(1)
+		}
+	})
+
+	// Optionally expose Prometheus metrics and the health checks, e.g. METRICS_ADDR=:9090
+	if metricsAddr := utils.GetEnv("METRICS_ADDR", ""); metricsAddr != "" {
+		go func() {
+			logger.Info("Serving metrics on %s/metrics and health checks on /healthz and /readyz", metricsAddr)
+			err := metrics.Serve(context.Background(), metricsAddr, map[string]http.Handler{
+				"/healthz": healthMonitor.LivenessHandler(),
+				"/readyz":  healthMonitor.ReadinessHandler(),
+			})
+			if err != nil {
+				logger.Error("Metrics server stopped: %v", err)
+			}
+		}()
+	}
+
+	// Create one swap service per pair
+	manager, err := swap.NewManager(cfg, client, solService, jupiterSvc, tokenSvc, registry, swap.Options{
+		History: store,
+		Risk:    riskManager,
+		Ledger:  tradeLedger,
+		Health:  healthMonitor,
+	})
 	if err != nil {
 		logger.Error("Failed to initialize swap service: %v", err)
 		log.Fatalf("Failed to initialize swap service: %v", err)
@@ -92,9 +298,125 @@ func main() {
 
 	logger.Info("Starting swap monitoring service")
 	// Start the swap monitoring service
-	err = swapService.Start(context.Background())
+	err = manager.Start(context.Background())
 	if err != nil {
 		logger.Error("Swap service error: %v", err)
 		log.Fatalf("Swap service error: %v", err)
 	}
 }
+
+// handleKillSignals halts trading on SIGUSR1 and resumes it on SIGUSR2
+func handleKillSignals(riskManager *risk.Manager) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+	for sig := range signals {
+		if sig == syscall.SIGUSR1 {
+			riskManager.Halt("received SIGUSR1")
+		} else {
+			riskManager.Resume("received SIGUSR2")
+		}
+	}
+}
+
+// logOptions reads the format and minimum level of the console and file logs from the environment
+func logOptions() logger.Options {
+	opts := logger.DefaultOptions()
+	opts.ConsoleFormat = utils.GetEnv("LOG_FORMAT", opts.ConsoleFormat)
+	opts.FileFormat = utils.GetEnv("LOG_FILE_FORMAT", opts.FileFormat)
+
+	var err error
+	if opts.ConsoleLevel, err = logger.ParseLevel(utils.GetEnv("LOG_LEVEL", "info")); err != nil {
+		log.Printf("Using info console log level: %v", err)
+	}
+	if opts.FileLevel, err = logger.ParseLevel(utils.GetEnv("LOG_FILE_LEVEL", "info")); err != nil {
+		log.Printf("Using info file log level: %v", err)
+	}
+
+	// Rotation of the log files; set LOG_REOPEN_ON_SIGHUP=true when an external logrotate moves them
+	opts.Rotation.MaxSizeBytes = int64(utils.GetEnvFloat("LOG_MAX_SIZE_MB", 50) * (1 << 20))
+	opts.Rotation.Interval = time.Duration(utils.GetEnvFloat("LOG_ROTATE_HOURS", 0) * float64(time.Hour))
+	opts.Rotation.Compress = utils.GetEnv("LOG_COMPRESS", "true") == "true"
+	opts.Rotation.MaxBackups = int(utils.GetEnvFloat("LOG_MAX_BACKUPS", 10))
+	opts.Rotation.MaxAge = time.Duration(utils.GetEnvFloat("LOG_MAX_AGE_DAYS", 0) * 24 * float64(time.Hour))
+	opts.Rotation.ReopenOnSIGHUP = utils.GetEnv("LOG_REOPEN_ON_SIGHUP", "false") == "true"
+	return opts
+}
+
+// splitList splits a comma separated list, dropping empty entries
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// takeProfitLevels parses a comma separated list of "percent:fraction" take-profit levels
+func takeProfitLevels(spec string) ([]datatypes.TakeProfitLevel, error) {
+	var levels []datatypes.TakeProfitLevel
+	for _, entry := range splitList(spec) {
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("take-profit level %q is not percent:fraction", entry)
+		}
+		percent, err := utils.ParseFloat(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid percent in take-profit level %q: %v", entry, err)
+		}
+		fraction, err := utils.ParseFloat(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid fraction in take-profit level %q: %v", entry, err)
+		}
+		levels = append(levels, datatypes.TakeProfitLevel{PercentAboveEntry: percent, Fraction: fraction})
+	}
+	return levels, nil
+}
+
+// checkStopLoss requires an explicit stop loss price and adjustment for every pair whose risk
+// asset isn't SOL, since the defaults are SOL prices and would trigger a sell immediately.
+// priceSet and adjustmentSet report whether the base values came from the environment.
+func checkStopLoss(cfg *datatypes.Config, priceSet, adjustmentSet bool) error {
+	pairs := []datatypes.PairConfig{{}}
+	if len(cfg.Pairs) > 0 {
+		pairs = cfg.Pairs
+	}
+
+	for _, pair := range pairs {
+		riskAsset := cfg.ForPair(pair).RiskAsset
+		if token.MintAddress(riskAsset) == token.WrappedSOLMint {
+			continue
+		}
+		if !priceSet && pair.StopLossPrice <= 0 {
+			return fmt.Errorf("no stop loss price for %s: set STOP_LOSS_PRICE or the pair's stopLossPrice in %s units",
+				riskAsset, cfg.ForPair(pair).QuoteAssets[0])
+		}
+		if cfg.DynamicStopLoss && !adjustmentSet && pair.StopLossAdjustment <= 0 {
+			return fmt.Errorf("no stop loss adjustment for %s: set STOP_LOSS_ADJUSTMENT or the pair's stopLossAdjustment", riskAsset)
+		}
+	}
+	return nil
+}
+
+// pairMints converts the configured risk and quote assets of every pair into mint addresses
+func pairMints(cfg *datatypes.Config) []string {
+	pairs := []datatypes.PairConfig{{RiskAsset: cfg.RiskAsset, QuoteAssets: cfg.QuoteAssets}}
+	if len(cfg.Pairs) > 0 {
+		pairs = cfg.Pairs
+	}
+
+	seen := make(map[string]bool)
+	var mints []string
+	for _, pair := range pairs {
+		pairCfg := cfg.ForPair(pair)
+		for _, asset := range append([]string{pairCfg.RiskAsset}, pairCfg.QuoteAssets...) {
+			mint := token.MintAddress(asset)
+			if !seen[mint] {
+				seen[mint] = true
+				mints = append(mints, mint)
+			}
+		}
+	}
+	return mints
+}
@@ -0,0 +1,107 @@
+// package indicators builds OHLC candles from price samples and computes technical indicators over them
+package indicators
+
+import (
+	"sync"
+	"time"
+)
+
+// Candle is an open/high/low/close bar covering one period starting at Time
+type Candle struct {
+	Time    time.Time `json:"time"`
+	Open    float64   `json:"open"`
+	High    float64   `json:"high"`
+	Low     float64   `json:"low"`
+	Close   float64   `json:"close"`
+	Samples int       `json:"samples"`
+}
+
+// update folds a price sample into the candle
+func (c *Candle) update(price float64) {
+	if price > c.High {
+		c.High = price
+	}
+	if price < c.Low {
+		c.Low = price
+	}
+	c.Close = price
+	c.Samples++
+}
+
+// Aggregator builds fixed-period candles from price samples and keeps the most recent closed ones
+type Aggregator struct {
+	mu      sync.Mutex
+	period  time.Duration
+	limit   int
+	current *Candle
+	closed  []Candle
+}
+
+// NewAggregator creates an aggregator for the given candle period that keeps up to limit closed candles
+func NewAggregator(period time.Duration, limit int) *Aggregator {
+	return &Aggregator{
+		period: period,
+		limit:  limit,
+	}
+}
+
+// Period returns the candle period
+func (a *Aggregator) Period() time.Duration {
+	return a.period
+}
+
+// Limit returns how many closed candles are kept (0 for all)
+func (a *Aggregator) Limit() int {
+	return a.limit
+}
+
+// Add records a price sample. When the sample falls into a new period, the previous
+// candle is closed and returned with ok set to true.
+func (a *Aggregator) Add(t time.Time, price float64) (closed Candle, ok bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	start := t.Truncate(a.period)
+	if a.current != nil && start.After(a.current.Time) {
+		closed, ok = *a.current, true
+		a.closed = append(a.closed, closed)
+		if a.limit > 0 && len(a.closed) > a.limit {
+			a.closed = a.closed[len(a.closed)-a.limit:]
+		}
+		a.current = nil
+	}
+
+	if a.current == nil {
+		a.current = &Candle{Time: start, Open: price, High: price, Low: price, Close: price, Samples: 1}
+		return closed, ok
+	}
+	a.current.update(price)
+	return closed, ok
+}
+
+// Candles returns the closed candles from oldest to newest
+func (a *Aggregator) Candles() []Candle {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]Candle(nil), a.closed...)
+}
+
+// Load seeds the aggregator with previously closed candles, e.g. from a history store
+func (a *Aggregator) Load(candles []Candle) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.closed = append([]Candle(nil), candles...)
+	if a.limit > 0 && len(a.closed) > a.limit {
+		a.closed = a.closed[len(a.closed)-a.limit:]
+	}
+}
+
+// Closes returns the closing prices of a candle series
+func Closes(candles []Candle) []float64 {
+	closes := make([]float64, len(candles))
+	for i, candle := range candles {
+		closes[i] = candle.Close
+	}
+	return closes
+}
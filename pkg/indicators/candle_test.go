@@ -0,0 +1,96 @@
+package indicators
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAggregatorBuildsCandles(t *testing.T) {
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	a := NewAggregator(time.Minute, 0)
+
+	samples := []struct {
+		offset time.Duration
+		price  float64
+		closes bool
+	}{
+		{0, 10, false},
+		{20 * time.Second, 12, false},
+		{40 * time.Second, 9, false},
+		{59 * time.Second, 11, false},
+		{60 * time.Second, 11.5, true},
+		{3 * time.Minute, 13, true}, // Skipped periods produce no candles
+	}
+	var closed []Candle
+	for _, sample := range samples {
+		candle, ok := a.Add(start.Add(sample.offset), sample.price)
+		if ok != sample.closes {
+			t.Fatalf("Add at %s closed = %v, want %v", sample.offset, ok, sample.closes)
+		}
+		if ok {
+			closed = append(closed, candle)
+		}
+	}
+
+	want := []Candle{
+		{Time: start, Open: 10, High: 12, Low: 9, Close: 11, Samples: 4},
+		{Time: start.Add(time.Minute), Open: 11.5, High: 11.5, Low: 11.5, Close: 11.5, Samples: 1},
+	}
+	got := a.Candles()
+	if len(got) != len(want) || len(closed) != len(want) {
+		t.Fatalf("got %d candles and %d closed, want %d", len(got), len(closed), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] || closed[i] != want[i] {
+			t.Errorf("candle %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if closes := Closes(got); closes[0] != 11 || closes[1] != 11.5 {
+		t.Errorf("Closes = %v, want [11 11.5]", closes)
+	}
+}
+
+func TestAggregatorKeepsLimit(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	a := NewAggregator(time.Minute, 2)
+	for i := 0; i < 5; i++ {
+		a.Add(start.Add(time.Duration(i)*time.Minute), float64(i))
+	}
+
+	candles := a.Candles()
+	if len(candles) != 2 || candles[0].Close != 2 || candles[1].Close != 3 {
+		t.Errorf("Candles = %+v, want the closes 2 and 3", candles)
+	}
+}
+
+func TestAggregatorLoad(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	history := []Candle{
+		{Time: start, Close: 1},
+		{Time: start.Add(time.Minute), Close: 2},
+		{Time: start.Add(2 * time.Minute), Close: 3},
+	}
+	a := NewAggregator(time.Minute, 2)
+	a.Load(history)
+	history[2].Close = 99 // Load must copy
+
+	candles := a.Candles()
+	if len(candles) != 2 || candles[0].Close != 2 || candles[1].Close != 3 {
+		t.Errorf("Candles after Load = %+v, want the last two", candles)
+	}
+
+	// Samples before the first close are still aggregated into the open candle
+	if _, ok := a.Add(start.Add(3*time.Minute), 4); ok {
+		t.Error("first sample after Load closed a candle")
+	}
+}
+
+func TestAggregatorEmpty(t *testing.T) {
+	a := NewAggregator(5*time.Minute, 10)
+	if candles := a.Candles(); len(candles) != 0 {
+		t.Errorf("Candles of an empty aggregator = %+v", candles)
+	}
+	if a.Period() != 5*time.Minute {
+		t.Errorf("Period = %s", a.Period())
+	}
+}
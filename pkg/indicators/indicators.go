@@ -0,0 +1,131 @@
+package indicators
+
+import "math"
+
+// SMA returns the simple moving average of the last period values.
+// ok is false when there are fewer values than the period.
+func SMA(values []float64, period int) (float64, bool) {
+	if period <= 0 || len(values) < period {
+		return 0, false
+	}
+	sum := 0.0
+	for _, value := range values[len(values)-period:] {
+		sum += value
+	}
+	return sum / float64(period), true
+}
+
+// EMA returns the exponential moving average over the values, seeded with the SMA
+// of the first period values and smoothed with 2/(period+1)
+func EMA(values []float64, period int) (float64, bool) {
+	if period <= 0 || len(values) < period {
+		return 0, false
+	}
+
+	ema, _ := SMA(values[:period], period)
+
+	k := 2 / float64(period+1)
+	for _, value := range values[period:] {
+		ema = value*k + ema*(1-k)
+	}
+	return ema, true
+}
+
+// RSI returns the relative strength index using Wilder's smoothing. It needs
+// at least period+1 values, since it is computed over price changes.
+func RSI(values []float64, period int) (float64, bool) {
+	if period <= 0 || len(values) <= period {
+		return 0, false
+	}
+
+	var gain, loss float64
+	for i := 1; i <= period; i++ {
+		change := values[i] - values[i-1]
+		if change > 0 {
+			gain += change
+		} else {
+			loss -= change
+		}
+	}
+	gain /= float64(period)
+	loss /= float64(period)
+
+	for i := period + 1; i < len(values); i++ {
+		change := values[i] - values[i-1]
+		var up, down float64
+		if change > 0 {
+			up = change
+		} else {
+			down = -change
+		}
+		gain = (gain*float64(period-1) + up) / float64(period)
+		loss = (loss*float64(period-1) + down) / float64(period)
+	}
+
+	if loss == 0 {
+		return 100, true
+	}
+	return 100 - 100/(1+gain/loss), true
+}
+
+// ATR returns the average true range of the candles using Wilder's smoothing. A candle's
+// true range also covers a gap from the previous close, so it needs at least period+1 candles.
+func ATR(candles []Candle, period int) (float64, bool) {
+	if period <= 0 || len(candles) <= period {
+		return 0, false
+	}
+
+	trueRange := func(i int) float64 {
+		prevClose := candles[i-1].Close
+		return math.Max(candles[i].High-candles[i].Low,
+			math.Max(math.Abs(candles[i].High-prevClose), math.Abs(candles[i].Low-prevClose)))
+	}
+
+	atr := 0.0
+	for i := 1; i <= period; i++ {
+		atr += trueRange(i)
+	}
+	atr /= float64(period)
+
+	for i := period + 1; i < len(candles); i++ {
+		atr = (atr*float64(period-1) + trueRange(i)) / float64(period)
+	}
+	return atr, true
+}
+
+// StdDev returns the population standard deviation of the last period values
+func StdDev(values []float64, period int) (float64, bool) {
+	mean, ok := SMA(values, period)
+	if !ok {
+		return 0, false
+	}
+
+	variance := 0.0
+	for _, value := range values[len(values)-period:] {
+		variance += (value - mean) * (value - mean)
+	}
+	return math.Sqrt(variance / float64(period)), true
+}
+
+// Bands are Bollinger bands around a simple moving average
+type Bands struct {
+	Upper  float64
+	Middle float64
+	Lower  float64
+}
+
+// Bollinger returns the Bollinger bands over the last period values, placed
+// multiplier population standard deviations above and below the SMA
+func Bollinger(values []float64, period int, multiplier float64) (Bands, bool) {
+	middle, ok := SMA(values, period)
+	if !ok {
+		return Bands{}, false
+	}
+	deviation, _ := StdDev(values, period)
+
+	return Bands{
+		Upper:  middle + multiplier*deviation,
+		Middle: middle,
+		Lower:  middle - multiplier*deviation,
+	}, true
+}
@@ -0,0 +1,237 @@
+package indicators
+
+import (
+	"math"
+	"testing"
+)
+
+// Reference series from the StockCharts ChartSchool worked examples
+var (
+	// emaSeries is the 10-day EMA example
+	emaSeries = []float64{
+		22.27, 22.19, 22.08, 22.17, 22.18, 22.13, 22.23, 22.43, 22.24, 22.29,
+		22.15, 22.39, 22.38, 22.61, 23.36, 24.05, 23.75, 23.83, 23.95, 23.63,
+		23.82, 23.87, 23.65, 23.19, 23.10, 23.33, 22.68, 23.10, 22.40, 22.17,
+	}
+	// emaReference are the published 10-day EMAs, starting at the 10th value
+	emaReference = []float64{
+		22.22, 22.21, 22.24, 22.27, 22.33, 22.52, 22.80, 22.97, 23.13, 23.28,
+		23.34, 23.43, 23.51, 23.53, 23.47, 23.40, 23.39, 23.26, 23.23, 23.08, 22.92,
+	}
+
+	// rsiSeries is the 14-day RSI example
+	rsiSeries = []float64{
+		44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08,
+		45.89, 46.03, 45.61, 46.28, 46.28, 46.00, 46.03, 46.41, 46.22, 45.64,
+		46.21, 46.25, 45.71, 46.45, 45.78, 45.35, 44.03, 44.18, 44.22, 44.57,
+		43.42, 42.66, 43.13,
+	}
+	// rsiReference are the published 14-day RSIs, starting at the 15th value
+	rsiReference = []float64{
+		70.46, 66.25, 66.48, 69.35, 66.29, 57.92, 62.88, 63.21, 56.01, 62.34,
+		54.67, 50.39, 40.02, 41.49, 41.90, 45.50, 37.32, 33.09, 37.79,
+	}
+)
+
+// near reports whether got matches a reference value rounded to two decimals
+func near(got, want float64) bool {
+	return math.Abs(got-want) <= 0.005+1e-9
+}
+
+func TestSMA(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		period int
+		want   float64
+		ok     bool
+	}{
+		{"first window", emaSeries[:10], 10, 22.22, true},
+		{"last window", emaSeries, 10, 23.13, true},
+		{"single value", []float64{5}, 1, 5, true},
+		{"warm-up", emaSeries[:9], 10, 0, false},
+		{"empty", nil, 3, 0, false},
+		{"zero period", emaSeries, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := SMA(tt.values, tt.period)
+			if ok != tt.ok || !near(got, tt.want) {
+				t.Errorf("SMA = %v, %v; want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestEMAMatchesReference(t *testing.T) {
+	for i, want := range emaReference {
+		n := 10 + i
+		got, ok := EMA(emaSeries[:n], 10)
+		if !ok || !near(got, want) {
+			t.Errorf("EMA of first %d values = %.4f, %v; want %.2f", n, got, ok, want)
+		}
+	}
+}
+
+func TestEMAEdgeCases(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		period int
+		want   float64
+		ok     bool
+	}{
+		{"warm-up", emaSeries[:9], 10, 0, false},
+		{"seeded with the SMA", emaSeries[:10], 10, 22.221, true},
+		{"period one follows the price", []float64{1, 2, 3}, 1, 3, true},
+		{"zero period", emaSeries, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := EMA(tt.values, tt.period)
+			if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("EMA = %v, %v; want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestRSIMatchesReference(t *testing.T) {
+	for i, want := range rsiReference {
+		n := 15 + i
+		got, ok := RSI(rsiSeries[:n], 14)
+		if !ok || !near(got, want) {
+			t.Errorf("RSI of first %d values = %.4f, %v; want %.2f", n, got, ok, want)
+		}
+	}
+}
+
+func TestRSIEdgeCases(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		period int
+		want   float64
+		ok     bool
+	}{
+		{"needs period+1 values", rsiSeries[:14], 14, 0, false},
+		{"only gains", []float64{1, 2, 3, 4}, 3, 100, true},
+		{"only losses", []float64{4, 3, 2, 1}, 3, 0, true},
+		{"equal gains and losses", []float64{1, 2, 1}, 2, 50, true},
+		{"zero period", rsiSeries, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := RSI(tt.values, tt.period)
+			if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("RSI = %v, %v; want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestBollinger(t *testing.T) {
+	tests := []struct {
+		name       string
+		values     []float64
+		period     int
+		multiplier float64
+		want       Bands
+		ok         bool
+	}{
+		// Population standard deviation of 2, 4, 4, 4, 5, 5, 7, 9 is exactly 2
+		{"population deviation", []float64{2, 4, 4, 4, 5, 5, 7, 9}, 8, 2, Bands{Upper: 9, Middle: 5, Lower: 1}, true},
+		{"only the last period values", []float64{100, 2, 4, 4, 4, 5, 5, 7, 9}, 8, 1, Bands{Upper: 7, Middle: 5, Lower: 3}, true},
+		{"flat series collapses the bands", []float64{3, 3, 3}, 3, 2, Bands{Upper: 3, Middle: 3, Lower: 3}, true},
+		{"warm-up", []float64{1, 2}, 3, 2, Bands{}, false},
+		{"zero period", []float64{1, 2}, 0, 2, Bands{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Bollinger(tt.values, tt.period, tt.multiplier)
+			if ok != tt.ok ||
+				math.Abs(got.Upper-tt.want.Upper) > 1e-9 ||
+				math.Abs(got.Middle-tt.want.Middle) > 1e-9 ||
+				math.Abs(got.Lower-tt.want.Lower) > 1e-9 {
+				t.Errorf("Bollinger = %+v, %v; want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestATR(t *testing.T) {
+	// True ranges after the first candle: 1.5 (gap above the close), 0.6 (high-low), 1.6 (gap)
+	candles := []Candle{
+		{High: 10, Low: 9, Close: 9.5},
+		{High: 11, Low: 10, Close: 10.5},
+		{High: 10.8, Low: 10.2, Close: 10.4},
+		{High: 12, Low: 11, Close: 11.8},
+	}
+	flat := []Candle{
+		{High: 101, Low: 99, Close: 100},
+		{High: 101, Low: 99, Close: 100},
+		{High: 101, Low: 99, Close: 100},
+	}
+
+	tests := []struct {
+		name    string
+		candles []Candle
+		period  int
+		want    float64
+		ok      bool
+	}{
+		{"seeded with the average true range", candles[:3], 2, 1.05, true},
+		{"wilder smoothing", candles, 2, 1.325, true},
+		{"period of one is the last true range", candles, 1, 1.6, true},
+		{"constant range", flat, 2, 2, true},
+		{"warm-up needs period+1 candles", candles[:2], 2, 0, false},
+		{"empty", nil, 2, 0, false},
+		{"zero period", candles, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ATR(tt.candles, tt.period)
+			if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("ATR = %v, %v; want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestStdDev(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		period int
+		want   float64
+		ok     bool
+	}{
+		{"population deviation", []float64{2, 4, 4, 4, 5, 5, 7, 9}, 8, 2, true},
+		{"only the last period values", []float64{100, 2, 4, 4, 4, 5, 5, 7, 9}, 8, 2, true},
+		{"flat series", []float64{3, 3, 3}, 3, 0, true},
+		{"warm-up", []float64{1, 2}, 3, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := StdDev(tt.values, tt.period)
+			if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("StdDev = %v, %v; want %v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestIndicatorsAreDeterministic(t *testing.T) {
+	values := append([]float64(nil), rsiSeries...)
+	first, _ := RSI(values, 14)
+	for i := 0; i < 10; i++ {
+		if got, _ := RSI(values, 14); got != first {
+			t.Fatalf("RSI changed between runs: %v != %v", got, first)
+		}
+	}
+	for i := range values {
+		if values[i] != rsiSeries[i] {
+			t.Fatalf("RSI modified its input at %d", i)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+package logger
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Swap statuses recorded in the audit log. Success and failure are terminal.
+const (
+	SwapAttempt = "ATTEMPT"
+	SwapSent    = "SENT"
+	SwapSuccess = "SUCCESS"
+	SwapFailed  = "FAILED"
+)
+
+// auditQueueSize bounds the records waiting to be written. Writers block once it is full.
+const auditQueueSize = 256
+
+// ErrAuditLogClosed is returned when recording to a closed audit log
+var ErrAuditLogClosed = errors.New("audit log closed")
+
+// SwapRecord is one status change of a swap in the audit log
+type SwapRecord struct {
+	Time        time.Time `json:"time"`
+	SwapID      string    `json:"swapId"`
+	Status      string    `json:"status"`
+	InputMint   string    `json:"inputMint"`
+	OutputMint  string    `json:"outputMint"`
+	Amount      uint64    `json:"amount"`
+	SlippageBps int       `json:"slippageBps"`
+	Mode        string    `json:"mode,omitempty"`
+	Signature   string    `json:"signature,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Terminal reports whether the record ends its swap
+func (r SwapRecord) Terminal() bool {
+	return r.Status == SwapSuccess || r.Status == SwapFailed
+}
+
+// auditRequest is a record queued for the writer, with a channel for the result of a durable write
+type auditRequest struct {
+	record SwapRecord
+	done   chan error
+}
+
+// AuditLog is an append-only JSON lines log of swap status changes. Records are written in
+// the order they are submitted by a single writer; terminal records are fsynced before
+// Record returns. When the writer falls behind, Record blocks instead of dropping records.
+type AuditLog struct {
+	file  *rotatingFile
+	queue chan auditRequest
+	wg    sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+var (
+	// Default audit log instance
+	defaultAuditLog *AuditLog
+	auditLogOnce    sync.Once
+)
+
+// InitAuditLog initializes the default audit log with the rotation settings of opts.
+// Rotated audit files are compressed but never deleted.
+func InitAuditLog(filePath string, opts Options) error {
+	var err error
+	auditLogOnce.Do(func() {
+		rotation := opts.Rotation
+		rotation.MaxBackups = 0
+		rotation.MaxAge = 0
+		defaultAuditLog, err = OpenAuditLog(filePath, rotation)
+	})
+	return err
+}
+
+// OpenAuditLog opens the audit log at filePath for appending
+func OpenAuditLog(filePath string, rotation RotationOptions) (*AuditLog, error) {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %v", err)
+	}
+	file, err := openRotatingFile(filePath, rotation)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &AuditLog{
+		file:  file,
+		queue: make(chan auditRequest, auditQueueSize),
+	}
+	a.wg.Add(1)
+	go a.writer()
+	return a, nil
+}
+
+// writer writes queued records in order, syncing the file after terminal records
+func (a *AuditLog) writer() {
+	defer a.wg.Done()
+
+	for request := range a.queue {
+		err := a.write(request.record)
+		if request.done != nil {
+			request.done <- err
+		} else if err != nil {
+			log.Printf("[ERROR] Failed to write swap audit record: %v", err)
+		}
+	}
+}
+
+// write appends a record, syncing it to disk when it is terminal
+func (a *AuditLog) write(record SwapRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := a.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	if record.Terminal() {
+		return a.file.Sync()
+	}
+	return nil
+}
+
+// Record appends a record to the audit log. Terminal records are durable when Record returns.
+func (a *AuditLog) Record(record SwapRecord) error {
+	if record.Time.IsZero() {
+		record.Time = time.Now().UTC()
+	}
+
+	// The read lock keeps Close from closing the queue while a record is being submitted
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return ErrAuditLogClosed
+	}
+
+	request := auditRequest{record: record}
+	if record.Terminal() {
+		request.done = make(chan error, 1)
+	}
+	a.queue <- request
+	if request.done != nil {
+		return <-request.done
+	}
+	return nil
+}
+
+// Close writes the queued records and closes the file
+func (a *AuditLog) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	close(a.queue)
+	a.mu.Unlock()
+
+	a.wg.Wait()
+	if err := a.file.Sync(); err != nil && !errors.Is(err, os.ErrClosed) {
+		log.Printf("[ERROR] Failed to sync swap audit log: %v", err)
+	}
+	return a.file.Close()
+}
+
+// Path returns the path of the current audit log file
+func (a *AuditLog) Path() string {
+	return a.file.path
+}
+
+// RecordSwap appends a record to the default audit log. Swaps are not audited when
+// the audit log hasn't been initialized.
+func RecordSwap(record SwapRecord) {
+	if defaultAuditLog == nil {
+		return
+	}
+	if err := defaultAuditLog.Record(record); err != nil {
+		Error("Failed to record swap %s %s in the audit log: %v", record.SwapID, record.Status, err)
+	}
+}
+
+// CloseAuditLog closes the default audit log
+func CloseAuditLog() error {
+	if defaultAuditLog != nil {
+		return defaultAuditLog.Close()
+	}
+	return nil
+}
+
+// SwapSummary is the latest state of one swap in the audit log
+type SwapSummary struct {
+	SwapID      string
+	Started     time.Time
+	Updated     time.Time
+	Status      string
+	InputMint   string
+	OutputMint  string
+	Amount      uint64
+	SlippageBps int
+	Mode        string
+	Signature   string
+	Error       string
+}
+
+// Duration returns how long the swap took from its first to its latest record
+func (s SwapSummary) Duration() time.Duration {
+	return s.Updated.Sub(s.Started)
+}
+
+// ReadSwapRecords reads the audit log at path together with its rotated (and compressed)
+// files, oldest first
+func ReadSwapRecords(path string) ([]SwapRecord, error) {
+	rotated := &rotatingFile{path: path}
+	files, err := rotated.backups()
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, path)
+
+	var records []SwapRecord
+	for _, file := range files {
+		fileRecords, err := readSwapRecordFile(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		records = append(records, fileRecords...)
+	}
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	return records, nil
+}
+
+// readSwapRecordFile reads the records of one audit log file, decompressing .gz files
+func readSwapRecordFile(path string) ([]SwapRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress %s: %v", path, err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	var records []SwapRecord
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record SwapRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// A crash can leave a partial last line; skip it rather than failing the whole log
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// SummarizeSwaps folds the records of each swap into its latest state, ordered by start time
+func SummarizeSwaps(records []SwapRecord) []SwapSummary {
+	index := make(map[string]int)
+	var swaps []SwapSummary
+	for _, record := range records {
+		i, ok := index[record.SwapID]
+		if !ok {
+			i = len(swaps)
+			index[record.SwapID] = i
+			swaps = append(swaps, SwapSummary{
+				SwapID:      record.SwapID,
+				Started:     record.Time,
+				InputMint:   record.InputMint,
+				OutputMint:  record.OutputMint,
+				Amount:      record.Amount,
+				SlippageBps: record.SlippageBps,
+				Mode:        record.Mode,
+			})
+		}
+
+		swap := &swaps[i]
+		swap.Updated = record.Time
+		swap.Status = record.Status
+		if record.Signature != "" {
+			swap.Signature = record.Signature
+		}
+		if record.Error != "" {
+			swap.Error = record.Error
+		}
+	}
+	return swaps
+}
+
+// FindSwap returns the latest state of the swap with the given ID or transaction signature
+func FindSwap(records []SwapRecord, idOrSignature string) (SwapSummary, bool) {
+	for _, swap := range SummarizeSwaps(records) {
+		if swap.SwapID == idOrSignature || (swap.Signature != "" && swap.Signature == idOrSignature) {
+			return swap, true
+		}
+	}
+	return SwapSummary{}, false
+}
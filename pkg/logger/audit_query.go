@@ -0,0 +1,226 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// followPollInterval is how often a followed audit log is checked for new records
+const followPollInterval = time.Second
+
+// SwapFilter selects swaps or records from the audit log. Zero fields match everything.
+type SwapFilter struct {
+	Status    string    // e.g. SUCCESS or FAILED, case insensitive
+	From      time.Time // Inclusive
+	To        time.Time // Exclusive
+	Mint      string    // Input or output mint
+	Signature string
+	SwapID    string
+}
+
+// MatchRecord reports whether a single audit record passes the filter
+func (f SwapFilter) MatchRecord(record SwapRecord) bool {
+	return f.match(record.Time, record.Status, record.InputMint, record.OutputMint, record.Signature, record.SwapID)
+}
+
+// MatchSwap reports whether a swap passes the filter, using its start time and latest status
+func (f SwapFilter) MatchSwap(swap SwapSummary) bool {
+	return f.match(swap.Started, swap.Status, swap.InputMint, swap.OutputMint, swap.Signature, swap.SwapID)
+}
+
+// match applies the filter to the fields shared by records and swaps
+func (f SwapFilter) match(t time.Time, status, inputMint, outputMint, signature, swapID string) bool {
+	if f.Status != "" && !strings.EqualFold(f.Status, status) {
+		return false
+	}
+	if !f.From.IsZero() && t.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !t.Before(f.To) {
+		return false
+	}
+	if f.Mint != "" && f.Mint != inputMint && f.Mint != outputMint {
+		return false
+	}
+	if f.Signature != "" && f.Signature != signature {
+		return false
+	}
+	return f.SwapID == "" || f.SwapID == swapID
+}
+
+// Error classes of failed swaps
+const (
+	ErrorClassQuote        = "quote"
+	ErrorClassBuild        = "swap_build"
+	ErrorClassSlippage     = "slippage"
+	ErrorClassFunds        = "insufficient_funds"
+	ErrorClassBlockhash    = "blockhash_expired"
+	ErrorClassConfirmation = "confirmation"
+	ErrorClassTimeout      = "timeout"
+	ErrorClassRateLimit    = "rate_limited"
+	ErrorClassOther        = "other"
+)
+
+// ErrorClass groups a swap error message into a coarse class for reporting
+func ErrorClass(message string) string {
+	msg := strings.ToLower(message)
+	switch {
+	case strings.Contains(msg, "429") || strings.Contains(msg, "rate limit") || strings.Contains(msg, "too many requests"):
+		return ErrorClassRateLimit
+	case strings.Contains(msg, "slippage") || strings.Contains(msg, "0x1771"):
+		return ErrorClassSlippage
+	case strings.Contains(msg, "insufficient"):
+		return ErrorClassFunds
+	case strings.Contains(msg, "blockhash"):
+		return ErrorClassBlockhash
+	case strings.Contains(msg, "deadline exceeded") || strings.Contains(msg, "timeout") || strings.Contains(msg, "timed out"):
+		return ErrorClassTimeout
+	case strings.Contains(msg, "verification") || strings.Contains(msg, "confirm"):
+		return ErrorClassConfirmation
+	case strings.Contains(msg, "quote"):
+		return ErrorClassQuote
+	case strings.Contains(msg, "swap"):
+		return ErrorClassBuild
+	default:
+		return ErrorClassOther
+	}
+}
+
+// SwapStats summarizes a set of swaps
+type SwapStats struct {
+	Total              int
+	Succeeded          int
+	Failed             int
+	Pending            int            // Swaps without a terminal record, e.g. interrupted by a restart
+	FailureClasses     map[string]int // Failed swaps per ErrorClass
+	AverageSlippageBps float64
+	AverageDuration    time.Duration // Of completed swaps
+}
+
+// SuccessRate returns the share of completed swaps that succeeded, from 0 to 1
+func (s SwapStats) SuccessRate() float64 {
+	completed := s.Succeeded + s.Failed
+	if completed == 0 {
+		return 0
+	}
+	return float64(s.Succeeded) / float64(completed)
+}
+
+// FailureClassesByCount returns the failure classes ordered from most to least frequent
+func (s SwapStats) FailureClassesByCount() []string {
+	classes := make([]string, 0, len(s.FailureClasses))
+	for class := range s.FailureClasses {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		if s.FailureClasses[classes[i]] != s.FailureClasses[classes[j]] {
+			return s.FailureClasses[classes[i]] > s.FailureClasses[classes[j]]
+		}
+		return classes[i] < classes[j]
+	})
+	return classes
+}
+
+// ComputeSwapStats counts outcomes, failure classes and average settings of the swaps
+func ComputeSwapStats(swaps []SwapSummary) SwapStats {
+	stats := SwapStats{FailureClasses: make(map[string]int)}
+	slippage := 0
+	var duration time.Duration
+
+	for _, swap := range swaps {
+		stats.Total++
+		slippage += swap.SlippageBps
+		switch swap.Status {
+		case SwapSuccess:
+			stats.Succeeded++
+			duration += swap.Duration()
+		case SwapFailed:
+			stats.Failed++
+			duration += swap.Duration()
+			stats.FailureClasses[ErrorClass(swap.Error)]++
+		default:
+			stats.Pending++
+		}
+	}
+
+	if stats.Total > 0 {
+		stats.AverageSlippageBps = float64(slippage) / float64(stats.Total)
+	}
+	if completed := stats.Succeeded + stats.Failed; completed > 0 {
+		stats.AverageDuration = duration / time.Duration(completed)
+	}
+	return stats
+}
+
+// FollowSwapRecords calls fn for every record appended to the audit log at path until the
+// context is cancelled, like tail -f. It starts at the end of the current file and picks up
+// the new file when the log is rotated.
+func FollowSwapRecords(ctx context.Context, path string, fn func(SwapRecord)) error {
+	var offset int64
+	if info, err := os.Stat(path); err == nil {
+		offset = info.Size()
+	}
+
+	ticker := time.NewTicker(followPollInterval)
+	defer ticker.Stop()
+
+	var partial []byte
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+
+		// A smaller file means the log was rotated and a new one started
+		if info.Size() < offset {
+			offset = 0
+			partial = nil
+		}
+		if info.Size() == offset {
+			continue
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			file.Close()
+			return err
+		}
+
+		reader := bufio.NewReader(file)
+		for {
+			line, err := reader.ReadBytes('\n')
+			offset += int64(len(line))
+			if err != nil {
+				// Keep an incomplete last line until the rest of it is written
+				partial = append(partial, line...)
+				break
+			}
+
+			line = append(partial, line...)
+			partial = nil
+			var record SwapRecord
+			if json.Unmarshal(line, &record) == nil {
+				fn(record)
+			}
+		}
+		file.Close()
+	}
+}
@@ -1,20 +1,113 @@
 package logger
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
-// Logger is a wrapper around Go's standard logger that writes to both
-// the terminal and a file
+// Level is the severity of a log entry
+type Level int
+
+// Log levels from least to most severe
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// String returns the lower case name of the level
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+// ParseLevel parses a level name such as "debug" or "WARN"
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info", "":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelInfo, fmt.Errorf("unknown log level %q", name)
+	}
+}
+
+// Output formats of a sink
+const (
+	FormatText   = "text"   // 2006/01/02 15:04:05.000000 [INFO] message key=value
+	FormatJSON   = "json"   // One JSON object per line
+	FormatLogfmt = "logfmt" // time=... level=info msg="message" key=value
+)
+
+// Fields are structured key/value pairs attached to log entries, e.g. price or swap_id
+type Fields map[string]interface{}
+
+// Sink is one destination of log entries with its own format and minimum level
+type Sink struct {
+	Writer   io.Writer
+	Format   string
+	MinLevel Level
+}
+
+// Options configure the console and file sinks of a logger
+type Options struct {
+	ConsoleFormat string
+	ConsoleLevel  Level
+	FileFormat    string
+	FileLevel     Level
+	Rotation      RotationOptions
+}
+
+// DefaultOptions logs readable text to the console and JSON lines to the file, both from INFO.
+// The file is rotated at 50 MB and the 10 most recent rotated files are kept compressed.
+func DefaultOptions() Options {
+	return Options{
+		ConsoleFormat: FormatText,
+		ConsoleLevel:  LevelInfo,
+		FileFormat:    FormatJSON,
+		FileLevel:     LevelInfo,
+		Rotation: RotationOptions{
+			MaxSizeBytes: 50 << 20,
+			Compress:     true,
+			MaxBackups:   10,
+		},
+	}
+}
+
+// core is the state shared by a logger and the loggers derived from it with With
+type core struct {
+	mu    sync.Mutex
+	sinks []Sink
+	file  *rotatingFile
+}
+
+// Logger writes leveled, structured entries to the terminal and a file
 type Logger struct {
-	stdLogger *log.Logger
-	file      *os.File
-	mu        sync.Mutex
+	core   *core
+	fields Fields
 }
 
 var (
@@ -23,11 +116,16 @@ var (
 	once          sync.Once
 )
 
-// Init initializes the default logger
+// Init initializes the default logger with the default options
 func Init(filePath string) error {
+	return InitWithOptions(filePath, DefaultOptions())
+}
+
+// InitWithOptions initializes the default logger
+func InitWithOptions(filePath string, opts Options) error {
 	var err error
 	once.Do(func() {
-		defaultLogger, err = NewLogger(filePath)
+		defaultLogger, err = NewLoggerWithOptions(filePath, opts)
 	})
 	return err
 }
@@ -41,8 +139,13 @@ func ensureLogDirectory() error {
 	return nil
 }
 
-// NewLogger creates a new logger that writes to both terminal and file
+// NewLogger creates a new logger that writes to both terminal and file with the default options
 func NewLogger(filePath string) (*Logger, error) {
+	return NewLoggerWithOptions(filePath, DefaultOptions())
+}
+
+// NewLoggerWithOptions creates a new logger that writes to both terminal and file
+func NewLoggerWithOptions(filePath string, opts Options) (*Logger, error) {
 	// Ensure logs directory exists
 	if err := ensureLogDirectory(); err != nil {
 		return nil, fmt.Errorf("failed to create logs directory: %v", err)
@@ -54,110 +157,208 @@ func NewLogger(filePath string) (*Logger, error) {
 	}
 
 	// Open the log file in append mode, create if it doesn't exist
-	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	file, err := openRotatingFile(filePath, opts.Rotation)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open log file: %v", err)
+		return nil, err
 	}
 
-	// Create a multi-writer that writes to both stdout and the file
-	multiWriter := io.MultiWriter(os.Stdout, file)
-
-	// Create a new logger with the multi-writer
-	stdLogger := log.New(multiWriter, "", log.LstdFlags|log.Lmicroseconds)
+	logger := NewSinkLogger(
+		Sink{Writer: os.Stdout, Format: opts.ConsoleFormat, MinLevel: opts.ConsoleLevel},
+		Sink{Writer: file, Format: opts.FileFormat, MinLevel: opts.FileLevel},
+	)
+	logger.core.file = file
+	return logger, nil
+}
 
-	return &Logger{
-		stdLogger: stdLogger,
-		file:      file,
-		mu:        sync.Mutex{},
-	}, nil
+// NewSinkLogger creates a logger writing to arbitrary sinks
+func NewSinkLogger(sinks ...Sink) *Logger {
+	return &Logger{core: &core{sinks: sinks}}
 }
 
 // Close closes the logger's file
 func (l *Logger) Close() error {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	if l.file != nil {
-		return l.file.Close()
+	l.core.mu.Lock()
+	defer l.core.mu.Unlock()
+	if l.core.file != nil {
+		return l.core.file.Close()
+	}
+	return nil
+}
+
+// Reopen reopens the logger's file, e.g. after it was moved by an external logrotate
+func (l *Logger) Reopen() error {
+	if l.core.file != nil {
+		return l.core.file.Reopen()
 	}
 	return nil
 }
 
+// With returns a logger that adds the fields to every entry. It shares the sinks of l.
+func (l *Logger) With(fields Fields) *Logger {
+	merged := make(Fields, len(l.fields)+len(fields))
+	for key, value := range l.fields {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	return &Logger{core: l.core, fields: merged}
+}
+
+// Log writes an entry with the logger's fields to every sink that accepts the level
+func (l *Logger) Log(level Level, msg string, fields Fields) {
+	if len(l.fields) > 0 {
+		fields = l.With(fields).fields
+	}
+	now := time.Now()
+
+	l.core.mu.Lock()
+	defer l.core.mu.Unlock()
+	for _, sink := range l.core.sinks {
+		if level < sink.MinLevel {
+			continue
+		}
+		line := formatEntry(sink.Format, now, level, msg, fields)
+		if _, err := io.WriteString(sink.Writer, line); err != nil {
+			log.Printf("[ERROR] Failed to write log entry: %v", err)
+		}
+	}
+}
+
 // Info logs an info message
 func (l *Logger) Info(format string, v ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.stdLogger.Printf("[INFO] "+format, v...)
+	l.Log(LevelInfo, fmt.Sprintf(format, v...), nil)
 }
 
 // Error logs an error message
 func (l *Logger) Error(format string, v ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.stdLogger.Printf("[ERROR] "+format, v...)
+	l.Log(LevelError, fmt.Sprintf(format, v...), nil)
 }
 
 // Debug logs a debug message
 func (l *Logger) Debug(format string, v ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.stdLogger.Printf("[DEBUG] "+format, v...)
+	l.Log(LevelDebug, fmt.Sprintf(format, v...), nil)
 }
 
 // Warn logs a warning message
 func (l *Logger) Warn(format string, v ...interface{}) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	l.stdLogger.Printf("[WARN] "+format, v...)
+	l.Log(LevelWarn, fmt.Sprintf(format, v...), nil)
+}
+
+// formatEntry renders an entry as a single line in the sink's format
+func formatEntry(format string, t time.Time, level Level, msg string, fields Fields) string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	switch format {
+	case FormatJSON:
+		// time, level and msg lead so entries read naturally; fields follow in key order
+		b.WriteString(`{"time":` + jsonString(t.Format(time.RFC3339Nano)))
+		b.WriteString(`,"level":` + jsonString(level.String()))
+		b.WriteString(`,"msg":` + jsonString(msg))
+		for _, key := range keys {
+			data, err := json.Marshal(jsonValue(fields[key]))
+			if err != nil {
+				data = []byte(jsonString(fmt.Sprint(fields[key])))
+			}
+			b.WriteString("," + jsonString(key) + ":" + string(data))
+		}
+		b.WriteByte('}')
+	case FormatLogfmt:
+		b.WriteString("time=" + t.Format(time.RFC3339Nano))
+		b.WriteString(" level=" + level.String())
+		b.WriteString(" msg=" + logfmtValue(msg))
+		for _, key := range keys {
+			b.WriteString(" " + key + "=" + logfmtValue(fmt.Sprint(fields[key])))
+		}
+	default:
+		b.WriteString(t.Format("2006/01/02 15:04:05.000000"))
+		b.WriteString(" [" + strings.ToUpper(level.String()) + "] " + msg)
+		for _, key := range keys {
+			b.WriteString(" " + key + "=" + logfmtValue(fmt.Sprint(fields[key])))
+		}
+	}
+	b.WriteByte('\n')
+	return b.String()
+}
+
+// jsonValue converts values that don't marshal usefully, such as errors, to strings
+func jsonValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return value
+	}
+}
+
+// jsonString encodes a string as a JSON string literal
+func jsonString(value string) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// logfmtValue quotes a value when it contains spaces, quotes or an equals sign
+func logfmtValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		return strconv.Quote(value)
+	}
+	return value
 }
 
 // Global functions that use the default logger
 
-// Info logs an info message to the default logger
-func Info(format string, v ...interface{}) {
+// defaultOrInit returns the default logger, initializing it with the default file path if needed
+func defaultOrInit() *Logger {
 	if defaultLogger == nil {
-		// Initialize with default file path if not already initialized
 		if err := Init("activity.txt"); err != nil {
 			log.Printf("[ERROR] Failed to initialize default logger: %v", err)
-			return
+			return nil
 		}
 	}
-	defaultLogger.Info(format, v...)
+	return defaultLogger
+}
+
+// With returns a logger derived from the default logger that adds the fields to every entry
+func With(fields Fields) *Logger {
+	if l := defaultOrInit(); l != nil {
+		return l.With(fields)
+	}
+	return NewSinkLogger()
+}
+
+// Info logs an info message to the default logger
+func Info(format string, v ...interface{}) {
+	if l := defaultOrInit(); l != nil {
+		l.Info(format, v...)
+	}
 }
 
 // Error logs an error message to the default logger
 func Error(format string, v ...interface{}) {
-	if defaultLogger == nil {
-		// Initialize with default file path if not already initialized
-		if err := Init("activity.txt"); err != nil {
-			log.Printf("[ERROR] Failed to initialize default logger: %v", err)
-			return
-		}
+	if l := defaultOrInit(); l != nil {
+		l.Error(format, v...)
 	}
-	defaultLogger.Error(format, v...)
 }
 
 // Debug logs a debug message to the default logger
 func Debug(format string, v ...interface{}) {
-	if defaultLogger == nil {
-		// Initialize with default file path if not already initialized
-		if err := Init("activity.txt"); err != nil {
-			log.Printf("[ERROR] Failed to initialize default logger: %v", err)
-			return
-		}
+	if l := defaultOrInit(); l != nil {
+		l.Debug(format, v...)
 	}
-	defaultLogger.Debug(format, v...)
 }
 
 // Warn logs a warning message to the default logger
 func Warn(format string, v ...interface{}) {
-	if defaultLogger == nil {
-		// Initialize with default file path if not already initialized
-		if err := Init("activity.txt"); err != nil {
-			log.Printf("[ERROR] Failed to initialize default logger: %v", err)
-			return
-		}
+	if l := defaultOrInit(); l != nil {
+		l.Warn(format, v...)
 	}
-	defaultLogger.Warn(format, v...)
 }
 
 // Close closes the default logger
@@ -0,0 +1,300 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// rotatedTimeFormat names rotated files so they sort chronologically, e.g. activity-20261018T115338.000.txt
+const rotatedTimeFormat = "20060102T150405.000"
+
+// RotationOptions control when a log file is rotated and how many rotated files are kept.
+// Zero values disable the respective limit.
+type RotationOptions struct {
+	MaxSizeBytes   int64         // Rotate once the file would grow beyond this size
+	Interval       time.Duration // Rotate when a write falls into a new interval, e.g. 24h for daily files
+	Compress       bool          // Gzip rotated files
+	MaxBackups     int           // Number of rotated files to keep
+	MaxAge         time.Duration // Delete rotated files older than this
+	ReopenOnSIGHUP bool          // Reopen the file on SIGHUP, for use with an external logrotate
+}
+
+// rotatingFile is an append-only log file that rotates itself according to its options
+type rotatingFile struct {
+	path string
+	opts RotationOptions
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	closed   bool
+
+	background sync.WaitGroup // Compression and cleanup of rotated files
+}
+
+var (
+	// reopenFiles are the files reopened on SIGHUP
+	reopenFiles   []*rotatingFile
+	reopenMu      sync.Mutex
+	reopenHandler sync.Once
+)
+
+// openRotatingFile opens path for appending, creating it if it doesn't exist
+func openRotatingFile(path string, opts RotationOptions) (*rotatingFile, error) {
+	f := &rotatingFile{path: path, opts: opts}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	if opts.ReopenOnSIGHUP {
+		reopenMu.Lock()
+		reopenFiles = append(reopenFiles, f)
+		reopenMu.Unlock()
+		reopenHandler.Do(func() { go handleReopenSignals() })
+	}
+	return f, nil
+}
+
+// open opens the file and records its size. The interval of an existing file
+// starts at its last write, so a file left over from yesterday rotates today.
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %v", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = time.Now()
+	if f.size > 0 {
+		f.openedAt = info.ModTime()
+	}
+	return nil
+}
+
+// Write appends p to the file, rotating it first when it is due
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.rotationDue(int64(len(p)), time.Now()) {
+		if err := f.rotate(); err != nil {
+			// Keep logging to the current file rather than losing entries
+			log.Printf("[ERROR] Failed to rotate %s: %v", f.path, err)
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotationDue reports whether writing n more bytes at now requires a new file
+func (f *rotatingFile) rotationDue(n int64, now time.Time) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.opts.MaxSizeBytes > 0 && f.size+n > f.opts.MaxSizeBytes {
+		return true
+	}
+	return f.opts.Interval > 0 && !now.Truncate(f.opts.Interval).Equal(f.openedAt.Truncate(f.opts.Interval))
+}
+
+// rotate renames the current file with a timestamp, opens a new one and compresses
+// and prunes rotated files in the background
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	rotated := f.rotatedName(time.Now())
+	if err := os.Rename(f.path, rotated); err != nil {
+		if openErr := f.open(); openErr != nil {
+			return fmt.Errorf("%v (and failed to reopen: %v)", err, openErr)
+		}
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	f.background.Add(1)
+	go func() {
+		defer f.background.Done()
+		if f.opts.Compress {
+			if err := compressFile(rotated); err != nil {
+				log.Printf("[ERROR] Failed to compress %s: %v", rotated, err)
+			}
+		}
+		f.prune()
+	}()
+	return nil
+}
+
+// rotatedName returns the name of the file rotated at t, e.g. logs/activity-20261018T115338.000.txt
+func (f *rotatingFile) rotatedName(t time.Time) string {
+	ext := filepath.Ext(f.path)
+	base := strings.TrimSuffix(f.path, ext)
+	return base + "-" + t.UTC().Format(rotatedTimeFormat) + ext
+}
+
+// backups returns the rotated files of the log, oldest first
+func (f *rotatingFile) backups() ([]string, error) {
+	ext := filepath.Ext(f.path)
+	base := strings.TrimSuffix(f.path, ext)
+	matches, err := filepath.Glob(base + "-*" + ext + "*")
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []string
+	for _, match := range matches {
+		stamp := strings.TrimPrefix(match, base+"-")
+		stamp = strings.TrimSuffix(strings.TrimSuffix(stamp, ".gz"), ext)
+		if _, err := time.Parse(rotatedTimeFormat, stamp); err == nil {
+			backups = append(backups, match)
+		}
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// prune deletes rotated files beyond the retention count or age
+func (f *rotatingFile) prune() {
+	if f.opts.MaxBackups <= 0 && f.opts.MaxAge <= 0 {
+		return
+	}
+
+	backups, err := f.backups()
+	if err != nil {
+		log.Printf("[ERROR] Failed to list rotated logs of %s: %v", f.path, err)
+		return
+	}
+
+	for i, backup := range backups {
+		expired := f.opts.MaxBackups > 0 && i < len(backups)-f.opts.MaxBackups
+		if !expired && f.opts.MaxAge > 0 {
+			if info, err := os.Stat(backup); err == nil && time.Since(info.ModTime()) > f.opts.MaxAge {
+				expired = true
+			}
+		}
+		if expired {
+			if err := os.Remove(backup); err != nil && !os.IsNotExist(err) {
+				log.Printf("[ERROR] Failed to remove rotated log %s: %v", backup, err)
+			}
+		}
+	}
+}
+
+// Sync commits the file's contents to stable storage
+func (f *rotatingFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	return f.file.Sync()
+}
+
+// Reopen closes and reopens the file at its path, e.g. after an external tool moved it away
+func (f *rotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	if f.file != nil {
+		if err := f.file.Close(); err != nil {
+			return err
+		}
+		f.file = nil
+	}
+	return f.open()
+}
+
+// Close closes the file and waits for background compression to finish
+func (f *rotatingFile) Close() error {
+	f.mu.Lock()
+	var err error
+	f.closed = true
+	if f.file != nil {
+		err = f.file.Close()
+		f.file = nil
+	}
+	f.mu.Unlock()
+
+	f.background.Wait()
+	return err
+}
+
+// compressFile gzips path into path.gz and removes the original
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+
+	src.Close()
+	return os.Remove(path)
+}
+
+// handleReopenSignals reopens every registered log file on SIGHUP
+func handleReopenSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		reopenMu.Lock()
+		files := append([]*rotatingFile(nil), reopenFiles...)
+		reopenMu.Unlock()
+
+		for _, f := range files {
+			if err := f.Reopen(); err != nil {
+				log.Printf("[ERROR] Failed to reopen %s: %v", f.path, err)
+			}
+		}
+	}
+}
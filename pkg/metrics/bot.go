@@ -0,0 +1,23 @@
+package metrics
+
+// Metrics of the trading loop, updated by the swap, Solana and Jupiter services
+var (
+	// Gauges of the latest cycle of each pair
+	Price        = Default.NewGauge("solcycle_price", "Latest price of the risk asset in quote units.", "pair")
+	StopPrice    = Default.NewGauge("solcycle_stop_price", "Effective stop loss price in quote units.", "pair")
+	HighestPrice = Default.NewGauge("solcycle_highest_price", "Highest price seen, which the trailing stop follows.", "pair")
+	InRisk       = Default.NewGauge("solcycle_in_risk_position", "1 while the pair holds the risk asset, 0 while it holds the quote asset.", "pair")
+	Balance      = Default.NewGauge("solcycle_balance", "Token balance available to the pair.", "pair", "asset")
+
+	// Counters
+	Cycles           = Default.NewCounter("solcycle_cycles_total", "Monitoring cycles run.", "pair")
+	PriceFetchErrors = Default.NewCounter("solcycle_price_fetch_errors_total", "Failed price fetches.", "pair")
+	Swaps            = Default.NewCounter("solcycle_swaps_total", "Swaps by final status.", "status")
+	SwapFailures     = Default.NewCounter("solcycle_swap_failures_total", "Failed swaps by error class.", "error_class")
+	SwapRetries      = Default.NewCounter("solcycle_swap_retries_total", "Swap attempts retried after a failure.", "pair")
+
+	// Latency histograms in seconds
+	PriceFetchLatency = Default.NewHistogram("solcycle_price_fetch_seconds", "Latency of price fetches.", DefaultBuckets)
+	QuoteLatency      = Default.NewHistogram("solcycle_quote_seconds", "Latency of Jupiter quote requests.", DefaultBuckets)
+	ConfirmationTime  = Default.NewHistogram("solcycle_confirmation_seconds", "Time from sending a swap transaction until its status was first seen finalized, polled every 2s.", DefaultBuckets)
+)
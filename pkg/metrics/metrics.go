@@ -0,0 +1,301 @@
+// package metrics exposes counters, gauges and histograms in the Prometheus text format
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBuckets are histogram bucket upper bounds in seconds suited to RPC and API latencies
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// metric is a named family of series that can render itself
+type metric interface {
+	write(w io.Writer)
+}
+
+// Registry holds the metrics exposed by a handler
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+	names   map[string]bool
+}
+
+// Default is the registry the bot's metrics are registered with
+var Default = NewRegistry()
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// register adds a metric, panicking on duplicate names like a misconfigured program should
+func (r *Registry) register(name string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[name] {
+		panic(fmt.Sprintf("metric %s registered twice", name))
+	}
+	r.names[name] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// Render writes every metric in the Prometheus text exposition format
+func (r *Registry) Render(w io.Writer) {
+	r.mu.Lock()
+	metrics := append([]metric(nil), r.metrics...)
+	r.mu.Unlock()
+
+	for _, m := range metrics {
+		m.write(w)
+	}
+}
+
+// Handler serves the registry's metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.Render(w)
+	})
+}
+
+// family is the shared part of a metric: its name, help text and label names
+type family struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+// header writes the HELP and TYPE lines
+func (f family) header(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+}
+
+// key joins label values into a map key, checking their number
+func (f family) key(values []string) string {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// labelString renders label pairs such as {pair="SOL/USDC"} for a series key
+func (f family) labelString(key string, extra ...string) string {
+	var pairs []string
+	if len(f.labels) > 0 {
+		for i, value := range strings.Split(key, "\xff") {
+			pairs = append(pairs, f.labels[i]+"="+strconv.Quote(value))
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+"="+strconv.Quote(extra[i+1]))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// sortedKeys returns the series keys of a map in a stable order
+func sortedKeys[V any](series map[string]V) []string {
+	keys := make([]string, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// formatValue formats a sample value the way Prometheus expects
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Counter is a monotonically increasing value per label combination
+type Counter struct {
+	family
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounter creates and registers a counter
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{family: family{name, help, "counter", labels}, values: make(map[string]float64)}
+	r.register(name, c)
+	return c
+}
+
+// Inc adds one to the series with the given label values
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v, which must not be negative, to the series with the given label values
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		return
+	}
+	key := c.key(labelValues)
+	c.mu.Lock()
+	c.values[key] += v
+	c.mu.Unlock()
+}
+
+// write renders the counter
+func (c *Counter) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.header(w)
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labelString(key), formatValue(c.values[key]))
+	}
+}
+
+// Gauge is a value that can go up and down per label combination
+type Gauge struct {
+	family
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewGauge creates and registers a gauge
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{family: family{name, help, "gauge", labels}, values: make(map[string]float64)}
+	r.register(name, g)
+	return g
+}
+
+// Set sets the series with the given label values
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	key := g.key(labelValues)
+	g.mu.Lock()
+	g.values[key] = v
+	g.mu.Unlock()
+}
+
+// write renders the gauge
+func (g *Gauge) write(w io.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.header(w)
+	for _, key := range sortedKeys(g.values) {
+		fmt.Fprintf(w, "%s%s %s\n", g.name, g.labelString(key), formatValue(g.values[key]))
+	}
+}
+
+// Histogram counts observations in cumulative buckets per label combination
+type Histogram struct {
+	family
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+// histogramSeries holds the observations of one label combination
+type histogramSeries struct {
+	counts []uint64 // Per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogram creates and registers a histogram with the given bucket upper bounds
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	h := &Histogram{
+		family:  family{name, help, "histogram", labels},
+		buckets: buckets,
+		series:  make(map[string]*histogramSeries),
+	}
+	r.register(name, h)
+	return h
+}
+
+// Observe records a value in the series with the given label values
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	key := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	series, ok := h.series[key]
+	if !ok {
+		series = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[key] = series
+	}
+	for i, bound := range h.buckets {
+		if v <= bound {
+			series.counts[i]++
+			break
+		}
+	}
+	series.count++
+	series.sum += v
+}
+
+// ObserveSince records the seconds elapsed since start
+func (h *Histogram) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// write renders the histogram's buckets, sum and count
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.header(w)
+	for _, key := range sortedKeys(h.series) {
+		series := h.series[key]
+		cumulative := uint64(0)
+		for i, bound := range h.buckets {
+			cumulative += series.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelString(key, "le", formatValue(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelString(key, "le", "+Inf"), series.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labelString(key), formatValue(series.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labelString(key), series.count)
+	}
+}
+
+// Serve exposes the default registry on addr under /metrics, along with any extra
+// handlers by path, until the context is cancelled
+func Serve(ctx context.Context, addr string, handlers map[string]http.Handler) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Default.Handler())
+	for path, handler := range handlers {
+		mux.Handle(path, handler)
+	}
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+// package execution splits large swaps into smaller child swaps to reduce price impact
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"swap/internal/datatypes"
+	"swap/internal/utils"
+	"swap/pkg/logger"
+	"swap/service/jupiter"
+)
+
+// ErrAborted is returned when the remaining slices of an order are cancelled
+var ErrAborted = errors.New("order aborted")
+
+// Order describes an ExactIn swap to be executed in slices
+type Order struct {
+	Params            jupiter.SwapParams
+	InputDecimals     uint8
+	OutputDecimals    uint8
+	Slices            int           // Number of child swaps; 1 executes the order in one swap
+	SliceDelay        time.Duration // Wait between child swaps
+	MaxPriceImpactPct float64       // Abort when a slice's quoted price impact exceeds this (0 disables)
+	MaxPriceMovePct   float64       // Abort when the quoted price moves this far below the first slice (0 disables)
+}
+
+// Report summarizes the execution of an order
+type Report struct {
+	SlicesPlanned int
+	SlicesFilled  int
+	InputFilled   datatypes.TokenAmount
+	OutputQuoted  datatypes.TokenAmount
+	Signatures    []string // Transaction signatures of the executed slices
+	AbortReason   string
+}
+
+// QuotedAveragePrice returns the average price quoted for the executed slices in output
+// units per input unit. The output actually received may differ within the slippage
+// tolerance; the trade ledger records the amounts measured from the confirmed transactions.
+func (r Report) QuotedAveragePrice() float64 {
+	if r.InputFilled.IsZero() {
+		return 0
+	}
+	return r.OutputQuoted.Float() / r.InputFilled.Float()
+}
+
+// Executor executes orders through Jupiter as a series of child swaps
+type Executor struct {
+	jupiterSvc *jupiter.Service
+}
+
+// NewExecutor creates a new order executor
+func NewExecutor(jupiterSvc *jupiter.Service) *Executor {
+	return &Executor{jupiterSvc: jupiterSvc}
+}
+
+// Execute splits the order into equal slices and swaps them one after another. Each slice is
+// quoted first; if its price impact is too high or the price has moved too far since the
+// first slice, the remaining slices are cancelled and ErrAborted is returned with the report.
+func (e *Executor) Execute(ctx context.Context, order Order) (Report, error) {
+	slices := order.Slices
+	if slices < 1 {
+		slices = 1
+	}
+
+	report := Report{
+		SlicesPlanned: slices,
+		InputFilled:   datatypes.TokenAmount{Decimals: order.InputDecimals},
+		OutputQuoted:  datatypes.TokenAmount{Decimals: order.OutputDecimals},
+	}
+
+	sliceAmount := order.Params.Amount / uint64(slices)
+	referencePrice := 0.0
+
+	for i := 0; i < slices; i++ {
+		if i > 0 && order.SliceDelay > 0 {
+			select {
+			case <-ctx.Done():
+				return report, ctx.Err()
+			case <-time.After(order.SliceDelay):
+			}
+		}
+
+		// The last slice picks up the rounding remainder
+		params := order.Params
+		params.Mode = jupiter.ExactIn
+		params.Amount = sliceAmount
+		if i == slices-1 {
+			params.Amount = order.Params.Amount - sliceAmount*uint64(slices-1)
+		}
+		if params.Amount == 0 {
+			continue
+		}
+
+		quote, err := e.jupiterSvc.GetQuote(ctx, params)
+		if err != nil {
+			return report, fmt.Errorf("failed to quote slice %d/%d: %v", i+1, slices, err)
+		}
+
+		outRaw, err := strconv.ParseUint(quote.OutAmount, 10, 64)
+		if err != nil {
+			return report, fmt.Errorf("invalid quote output amount %q: %v", quote.OutAmount, err)
+		}
+		in := datatypes.TokenAmount{Raw: params.Amount, Decimals: order.InputDecimals}
+		out := datatypes.TokenAmount{Raw: outRaw, Decimals: order.OutputDecimals}
+		price := out.Float() / in.Float()
+
+		impactPct, _ := utils.ParseFloat(quote.PriceImpactPct)
+		if order.MaxPriceImpactPct > 0 && impactPct > order.MaxPriceImpactPct {
+			report.AbortReason = fmt.Sprintf("slice %d price impact %.4f%% exceeds %.4f%%", i+1, impactPct, order.MaxPriceImpactPct)
+			return report, fmt.Errorf("%w: %s", ErrAborted, report.AbortReason)
+		}
+
+		if referencePrice == 0 {
+			referencePrice = price
+		}
+		movePct := (referencePrice - price) / referencePrice * 100
+		if order.MaxPriceMovePct > 0 && movePct > order.MaxPriceMovePct {
+			report.AbortReason = fmt.Sprintf("price moved %.4f%% since first slice, limit %.4f%%", movePct, order.MaxPriceMovePct)
+			return report, fmt.Errorf("%w: %s", ErrAborted, report.AbortReason)
+		}
+
+		logger.Info("Executing slice %d/%d: %s in, ~%s out (price %.6g, impact %.4f%%)",
+			i+1, slices, in, out, price, impactPct)
+		signature, err := e.jupiterSvc.SwapWithParams(ctx, params)
+		if err != nil {
+			return report, fmt.Errorf("slice %d/%d failed: %v", i+1, slices, err)
+		}
+
+		report.SlicesFilled++
+		report.Signatures = append(report.Signatures, signature)
+		report.InputFilled.Raw += in.Raw
+		report.OutputQuoted.Raw += out.Raw
+	}
+
+	logger.Info("Order complete: %d/%d slices, %s in, ~%s out, quoted average price %.6g",
+		report.SlicesFilled, report.SlicesPlanned, report.InputFilled, report.OutputQuoted, report.QuotedAveragePrice())
+	return report, nil
+}
+
+// SliceCount returns the number of slices needed so no slice is worth more than maxSliceValue
+func SliceCount(orderValue float64, maxSliceValue float64) int {
+	if maxSliceValue <= 0 || orderValue <= maxSliceValue {
+		return 1
+	}
+	return int(math.Ceil(orderValue / maxSliceValue))
+}
@@ -0,0 +1,304 @@
+// package health tracks the liveness of the pair loops and serves health and readiness checks
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"swap/pkg/logger"
+)
+
+const (
+	// defaultStallIntervals is how many check intervals may pass without a completed cycle
+	defaultStallIntervals = 5
+
+	// defaultSwapGrace is how long a swap in flight may hold up the loop, including
+	// confirmation waits, retries and sliced execution
+	defaultSwapGrace = 15 * time.Minute
+
+	// probeTimeout bounds the RPC reachability check of /readyz
+	probeTimeout = 3 * time.Second
+)
+
+// Config configures the liveness checks
+type Config struct {
+	StallIntervals int                             // Cycles that may be missed before a pair counts as stalled
+	SwapGrace      time.Duration                   // How long a swap in flight may delay the next cycle
+	Probe          func(ctx context.Context) error // Checks RPC reachability for /readyz; nil skips it
+}
+
+// PairStatus is the health of one pair loop
+type PairStatus struct {
+	Pair              string    `json:"pair"`
+	Interval          string    `json:"interval"`
+	LastCycle         time.Time `json:"lastCycle,omitzero"`          // Last completed cycle, successful or not
+	LastSuccess       time.Time `json:"lastSuccess,omitzero"`        // Last cycle that completed without an error
+	LastPrice         time.Time `json:"lastPrice,omitzero"`          // Last successful price fetch
+	PriceAgeSeconds   float64   `json:"priceAgeSeconds,omitempty"`   // Age of the last price
+	SwapInFlight      bool      `json:"swapInFlight"`                // A swap is executing or waiting for the wallet
+	SwapStartedAt     time.Time `json:"swapStartedAt,omitzero"`      // When the swap in flight started
+	StalledForSeconds float64   `json:"stalledForSeconds,omitempty"` // Time since the last cycle once stalled
+	Healthy           bool      `json:"healthy"`
+}
+
+// pairState is the tracked state of one pair loop
+type pairState struct {
+	interval    time.Duration
+	registered  time.Time
+	lastCycle   time.Time
+	lastSuccess time.Time
+	lastPrice   time.Time
+	swapStarted time.Time // Zero when no swap is in flight
+	swapEnded   time.Time // The end of a swap counts as progress until the cycle completes
+	stalled     bool      // Already reported by the watchdog
+}
+
+// Monitor tracks the cycles of every pair loop. A nil monitor ignores all updates,
+// so services can report to it unconditionally.
+type Monitor struct {
+	config Config
+
+	mu    sync.Mutex
+	pairs map[string]*pairState
+}
+
+// NewMonitor creates a monitor, applying defaults to unset config fields
+func NewMonitor(config Config) *Monitor {
+	if config.StallIntervals <= 0 {
+		config.StallIntervals = defaultStallIntervals
+	}
+	if config.SwapGrace <= 0 {
+		config.SwapGrace = defaultSwapGrace
+	}
+	return &Monitor{config: config, pairs: make(map[string]*pairState)}
+}
+
+// Register adds a pair loop running every interval. Its first cycle is due one
+// stall window after registration.
+func (m *Monitor) Register(pair string, interval time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pairs[pair] = &pairState{interval: interval, registered: time.Now()}
+}
+
+// update applies fn to a registered pair's state
+func (m *Monitor) update(pair string, fn func(state *pairState)) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if state, ok := m.pairs[pair]; ok {
+		fn(state)
+	}
+}
+
+// CycleCompleted records the end of a monitoring cycle and whether it succeeded
+func (m *Monitor) CycleCompleted(pair string, err error) {
+	now := time.Now()
+	m.update(pair, func(state *pairState) {
+		state.lastCycle = now
+		if err == nil {
+			state.lastSuccess = now
+		}
+		if state.stalled {
+			logger.Info("%s strategy recovered: cycle completed", pair)
+			state.stalled = false
+		}
+	})
+}
+
+// PriceUpdated records a successful price fetch
+func (m *Monitor) PriceUpdated(pair string) {
+	now := time.Now()
+	m.update(pair, func(state *pairState) { state.lastPrice = now })
+}
+
+// SwapStarted marks a swap in flight, including waiting for the wallet and retries
+func (m *Monitor) SwapStarted(pair string) {
+	now := time.Now()
+	m.update(pair, func(state *pairState) { state.swapStarted = now })
+}
+
+// SwapFinished clears the swap in flight
+func (m *Monitor) SwapFinished(pair string) {
+	now := time.Now()
+	m.update(pair, func(state *pairState) {
+		state.swapStarted = time.Time{}
+		state.swapEnded = now
+	})
+}
+
+// deadline returns when the pair counts as stalled without another completed cycle
+func (m *Monitor) deadline(state *pairState) time.Time {
+	last := state.lastCycle
+	if last.IsZero() {
+		last = state.registered
+	}
+	if state.swapEnded.After(last) {
+		last = state.swapEnded
+	}
+	deadline := last.Add(time.Duration(m.config.StallIntervals) * state.interval)
+	if !state.swapStarted.IsZero() {
+		if swapDeadline := state.swapStarted.Add(m.config.SwapGrace); swapDeadline.After(deadline) {
+			deadline = swapDeadline
+		}
+	}
+	return deadline
+}
+
+// Status returns the health of every pair, ordered by name
+func (m *Monitor) Status() []PairStatus {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	statuses := make([]PairStatus, 0, len(m.pairs))
+	for pair, state := range m.pairs {
+		status := PairStatus{
+			Pair:          pair,
+			Interval:      state.interval.String(),
+			LastCycle:     state.lastCycle,
+			LastSuccess:   state.lastSuccess,
+			LastPrice:     state.lastPrice,
+			SwapInFlight:  !state.swapStarted.IsZero(),
+			SwapStartedAt: state.swapStarted,
+			Healthy:       now.Before(m.deadline(state)),
+		}
+		if !state.lastPrice.IsZero() {
+			status.PriceAgeSeconds = now.Sub(state.lastPrice).Seconds()
+		}
+		if !status.Healthy {
+			since := state.lastCycle
+			if since.IsZero() {
+				since = state.registered
+			}
+			status.StalledForSeconds = now.Sub(since).Seconds()
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Pair < statuses[j].Pair })
+	return statuses
+}
+
+// Watch checks the pairs every second until the context is cancelled. The first time a
+// pair misses its deadline it is logged and onStall, if set, is called with its status.
+func (m *Monitor) Watch(ctx context.Context, onStall func(PairStatus)) {
+	if m == nil {
+		return
+	}
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, status := range m.stalledPairs() {
+			logger.Error("WATCHDOG: %s strategy has not completed a cycle for %.0fs (last cycle %s, swap in flight: %t)",
+				status.Pair, status.StalledForSeconds, formatTime(status.LastCycle), status.SwapInFlight)
+			if onStall != nil {
+				onStall(status)
+			}
+		}
+	}
+}
+
+// stalledPairs returns the pairs that became stalled since the last check
+func (m *Monitor) stalledPairs() []PairStatus {
+	var stalled []PairStatus
+	for _, status := range m.Status() {
+		if status.Healthy {
+			continue
+		}
+		newlyStalled := false
+		m.update(status.Pair, func(state *pairState) {
+			newlyStalled = !state.stalled
+			state.stalled = true
+		})
+		if newlyStalled {
+			stalled = append(stalled, status)
+		}
+	}
+	return stalled
+}
+
+// healthResponse is the body of the health endpoints
+type healthResponse struct {
+	Status string       `json:"status"`
+	RPC    string       `json:"rpc,omitempty"`
+	Pairs  []PairStatus `json:"pairs"`
+}
+
+// LivenessHandler serves /healthz: 200 while every pair loop completes cycles, 503 once one has stalled
+func (m *Monitor) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		response := healthResponse{Status: "ok", Pairs: m.Status()}
+		for _, status := range response.Pairs {
+			if !status.Healthy {
+				response.Status = "stalled"
+			}
+		}
+		writeResponse(w, response)
+	})
+}
+
+// ReadinessHandler serves /readyz: 200 once every pair has a price, no loop has stalled and
+// the RPC node is reachable
+func (m *Monitor) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response := healthResponse{Status: "ok", Pairs: m.Status()}
+		for _, status := range response.Pairs {
+			if !status.Healthy {
+				response.Status = "stalled"
+			} else if status.LastPrice.IsZero() && response.Status == "ok" {
+				response.Status = "starting"
+			}
+		}
+
+		if m != nil && m.config.Probe != nil {
+			ctx, cancel := context.WithTimeout(r.Context(), probeTimeout)
+			defer cancel()
+			if err := m.config.Probe(ctx); err != nil {
+				response.RPC = err.Error()
+				if response.Status == "ok" {
+					response.Status = "rpc unreachable"
+				}
+			} else {
+				response.RPC = "ok"
+			}
+		}
+
+		writeResponse(w, response)
+	})
+}
+
+// writeResponse writes the JSON response with 200 when its status is ok and 503 otherwise
+func writeResponse(w http.ResponseWriter, response healthResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	if response.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// formatTime formats a time for the watchdog log, or "never"
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format(time.RFC3339)
+}
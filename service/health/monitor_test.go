@@ -0,0 +1,192 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewMonitorDefaults(t *testing.T) {
+	m := NewMonitor(Config{})
+	if m.config.StallIntervals != defaultStallIntervals || m.config.SwapGrace != defaultSwapGrace {
+		t.Errorf("config = %+v, want %d intervals and %s grace", m.config, defaultStallIntervals, defaultSwapGrace)
+	}
+
+	m = NewMonitor(Config{StallIntervals: 3, SwapGrace: time.Hour})
+	if m.config.StallIntervals != 3 || m.config.SwapGrace != time.Hour {
+		t.Errorf("config = %+v, want 3 intervals and 1h grace", m.config)
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	m := NewMonitor(Config{StallIntervals: 5, SwapGrace: 15 * time.Minute})
+
+	tests := []struct {
+		name  string
+		state pairState
+		want  time.Time
+	}{
+		{
+			name:  "first cycle due one window after registration",
+			state: pairState{interval: time.Minute, registered: base},
+			want:  base.Add(5 * time.Minute),
+		},
+		{
+			name:  "window from the last cycle",
+			state: pairState{interval: time.Minute, registered: base, lastCycle: base.Add(10 * time.Minute)},
+			want:  base.Add(15 * time.Minute),
+		},
+		{
+			name:  "swap in flight extends to the grace period",
+			state: pairState{interval: time.Minute, registered: base, lastCycle: base, swapStarted: base.Add(time.Minute)},
+			want:  base.Add(16 * time.Minute),
+		},
+		{
+			name: "swap grace never shortens the window",
+			state: pairState{interval: 10 * time.Minute, registered: base, lastCycle: base.Add(time.Hour),
+				swapStarted: base},
+			want: base.Add(110 * time.Minute),
+		},
+		{
+			name: "finished swap counts as progress",
+			state: pairState{interval: time.Minute, registered: base, lastCycle: base,
+				swapEnded: base.Add(20 * time.Minute)},
+			want: base.Add(25 * time.Minute),
+		},
+		{
+			name: "cycle after the swap takes over",
+			state: pairState{interval: time.Minute, registered: base, lastCycle: base.Add(30 * time.Minute),
+				swapEnded: base.Add(20 * time.Minute)},
+			want: base.Add(35 * time.Minute),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.deadline(&tt.state); !got.Equal(tt.want) {
+				t.Errorf("deadline = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// backdate moves every recorded time of a pair into the past, as if d had passed
+func backdate(m *Monitor, pair string, d time.Duration) {
+	m.update(pair, func(state *pairState) {
+		for _, t := range []*time.Time{&state.registered, &state.lastCycle, &state.lastSuccess,
+			&state.lastPrice, &state.swapStarted, &state.swapEnded} {
+			if !t.IsZero() {
+				*t = t.Add(-d)
+			}
+		}
+	})
+}
+
+func TestStallAndRecovery(t *testing.T) {
+	m := NewMonitor(Config{StallIntervals: 5, SwapGrace: 15 * time.Minute})
+	m.Register("SOL/USDC", time.Minute)
+	m.Register("JUP/USDC", time.Minute)
+
+	m.CycleCompleted("SOL/USDC", nil)
+	m.CycleCompleted("JUP/USDC", nil)
+	if stalled := m.stalledPairs(); len(stalled) != 0 {
+		t.Fatalf("stalled right after a cycle: %+v", stalled)
+	}
+
+	// SOL/USDC misses its window, JUP/USDC keeps cycling
+	backdate(m, "SOL/USDC", 6*time.Minute)
+	stalled := m.stalledPairs()
+	if len(stalled) != 1 || stalled[0].Pair != "SOL/USDC" || stalled[0].Healthy {
+		t.Fatalf("stalled = %+v, want only SOL/USDC", stalled)
+	}
+	if stalled[0].StalledForSeconds < 360 {
+		t.Errorf("stalled for %.0fs, want at least 360s", stalled[0].StalledForSeconds)
+	}
+
+	// A stall is reported once
+	if stalled := m.stalledPairs(); len(stalled) != 0 {
+		t.Errorf("stall reported again: %+v", stalled)
+	}
+
+	// A failed cycle still proves the loop is alive
+	m.CycleCompleted("SOL/USDC", errors.New("price fetch failed"))
+	for _, status := range m.Status() {
+		if !status.Healthy {
+			t.Errorf("%s unhealthy after recovering", status.Pair)
+		}
+		if status.Pair == "SOL/USDC" && !status.LastSuccess.Before(status.LastCycle) {
+			t.Errorf("last success %s should predate the failed cycle %s", status.LastSuccess, status.LastCycle)
+		}
+	}
+
+	// After recovering, a new stall is reported again
+	backdate(m, "SOL/USDC", 6*time.Minute)
+	if stalled := m.stalledPairs(); len(stalled) != 1 {
+		t.Errorf("second stall not reported: %+v", stalled)
+	}
+}
+
+func TestSwapInFlight(t *testing.T) {
+	m := NewMonitor(Config{StallIntervals: 5, SwapGrace: 15 * time.Minute})
+	m.Register("SOL/USDC", time.Minute)
+	m.CycleCompleted("SOL/USDC", nil)
+	m.SwapStarted("SOL/USDC")
+
+	// A long confirmation within the grace period isn't a stall
+	backdate(m, "SOL/USDC", 10*time.Minute)
+	status := m.Status()[0]
+	if !status.Healthy || !status.SwapInFlight {
+		t.Fatalf("status = %+v, want healthy with a swap in flight", status)
+	}
+
+	// A swap hanging past the grace period is
+	backdate(m, "SOL/USDC", 6*time.Minute)
+	if status := m.Status()[0]; status.Healthy {
+		t.Fatalf("status = %+v, want stalled after the grace period", status)
+	}
+
+	// Finishing the swap restarts the window until the next cycle
+	m.SwapFinished("SOL/USDC")
+	status = m.Status()[0]
+	if !status.Healthy || status.SwapInFlight {
+		t.Errorf("status = %+v, want healthy without a swap in flight", status)
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	m := NewMonitor(Config{StallIntervals: 5})
+	m.Register("SOL/USDC", time.Minute)
+
+	get := func(handler http.Handler) int {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		return recorder.Code
+	}
+
+	if code := get(m.LivenessHandler()); code != http.StatusOK {
+		t.Errorf("liveness before the first price = %d, want 200", code)
+	}
+	if code := get(m.ReadinessHandler()); code != http.StatusServiceUnavailable {
+		t.Errorf("readiness before the first price = %d, want 503", code)
+	}
+
+	m.PriceUpdated("SOL/USDC")
+	if code := get(m.ReadinessHandler()); code != http.StatusOK {
+		t.Errorf("readiness with a price = %d, want 200", code)
+	}
+
+	m.config.Probe = func(ctx context.Context) error { return errors.New("connection refused") }
+	if code := get(m.ReadinessHandler()); code != http.StatusServiceUnavailable {
+		t.Errorf("readiness with the RPC down = %d, want 503", code)
+	}
+	m.config.Probe = nil
+
+	backdate(m, "SOL/USDC", 6*time.Minute)
+	if code := get(m.LivenessHandler()); code != http.StatusServiceUnavailable {
+		t.Errorf("liveness once stalled = %d, want 503", code)
+	}
+}
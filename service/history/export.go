@@ -0,0 +1,55 @@
+package history
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"swap/pkg/indicators"
+)
+
+// Export formats
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// ExportCandles writes candles to w as CSV (with a header row) or as a JSON array
+func ExportCandles(w io.Writer, format string, candles []indicators.Candle) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if candles == nil {
+			candles = []indicators.Candle{}
+		}
+		return encoder.Encode(candles)
+
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write([]string{"time", "open", "high", "low", "close", "samples"}); err != nil {
+			return err
+		}
+		for _, candle := range candles {
+			record := []string{
+				candle.Time.UTC().Format(time.RFC3339),
+				strconv.FormatFloat(candle.Open, 'f', -1, 64),
+				strconv.FormatFloat(candle.High, 'f', -1, 64),
+				strconv.FormatFloat(candle.Low, 'f', -1, 64),
+				strconv.FormatFloat(candle.Close, 'f', -1, 64),
+				strconv.Itoa(candle.Samples),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+}
@@ -0,0 +1,389 @@
+// package history records polled prices and OHLC candles in local files
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"swap/pkg/indicators"
+)
+
+// Resolutions are the candle periods the store maintains for every series
+var Resolutions = map[string]time.Duration{
+	"1m": time.Minute,
+	"5m": 5 * time.Minute,
+	"1h": time.Hour,
+}
+
+// samplesFile is the name of the raw sample file within a series directory
+const samplesFile = "samples.jsonl"
+
+// compactionInterval is how often a series' raw samples are checked against the retention
+const compactionInterval = 24 * time.Hour
+
+// Config configures a store
+type Config struct {
+	// SampleRetention is how long raw samples are kept (0 keeps them forever). Candles are
+	// always kept, so older prices remain available at candle resolution.
+	SampleRetention time.Duration
+}
+
+// Sample is a single polled price
+type Sample struct {
+	Time  time.Time `json:"time"`
+	Price float64   `json:"price"`
+}
+
+// Store is a file-backed time series database. Each series (e.g. a pair such as
+// SOL-USDC) has its own directory with an append-only JSON lines file for raw samples
+// and one for each candle resolution. Lines are in time order, so reads from a given
+// time seek to it instead of scanning the whole file. It is safe for concurrent use.
+type Store struct {
+	dir    string
+	config Config
+
+	mu          sync.Mutex
+	files       map[string]*os.File
+	aggregators map[string]map[string]*indicators.Aggregator
+	compacted   map[string]time.Time
+}
+
+// Open opens (creating if needed) a store in the given directory
+func Open(dir string, config Config) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %v", err)
+	}
+	return &Store{
+		dir:         dir,
+		config:      config,
+		files:       make(map[string]*os.File),
+		aggregators: make(map[string]map[string]*indicators.Aggregator),
+		compacted:   make(map[string]time.Time),
+	}, nil
+}
+
+// Close closes all open files
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var firstErr error
+	for path, file := range s.files {
+		if err := file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		delete(s.files, path)
+	}
+	return firstErr
+}
+
+// Record appends a price sample to a series and writes any candles it closes
+func (s *Store) Record(series string, t time.Time, price float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.config.SampleRetention > 0 && t.Sub(s.compacted[series]) >= compactionInterval {
+		if err := s.compact(series, t.Add(-s.config.SampleRetention)); err != nil {
+			return err
+		}
+		s.compacted[series] = t
+	}
+
+	if err := s.appendLine(series, samplesFile, Sample{Time: t.UTC(), Price: price}); err != nil {
+		return err
+	}
+
+	aggregators, ok := s.aggregators[series]
+	if !ok {
+		aggregators = make(map[string]*indicators.Aggregator)
+		for name, period := range Resolutions {
+			aggregators[name] = indicators.NewAggregator(period, 1)
+		}
+		s.aggregators[series] = aggregators
+	}
+
+	for name, aggregator := range aggregators {
+		if candle, closed := aggregator.Add(t.UTC(), price); closed {
+			if err := s.appendLine(series, candleFile(name), candle); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Candles returns the closed candles of a series at a resolution, oldest first, starting
+// at since (zero for all) and limited to the most recent limit candles (0 for no limit)
+func (s *Store) Candles(series, resolution string, since time.Time, limit int) ([]indicators.Candle, error) {
+	if _, ok := Resolutions[resolution]; !ok {
+		return nil, fmt.Errorf("unknown resolution %q", resolution)
+	}
+
+	var candles []indicators.Candle
+	err := s.readLines(series, candleFile(resolution), since, func(line []byte) error {
+		var candle indicators.Candle
+		if err := json.Unmarshal(line, &candle); err != nil {
+			return err
+		}
+		if candle.Time.Before(since) {
+			return nil
+		}
+		candles = append(candles, candle)
+		if limit > 0 && len(candles) > limit {
+			candles = candles[1:]
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return candles, nil
+}
+
+// Samples returns the raw samples of a series recorded in [from, to). A zero to means no upper bound.
+func (s *Store) Samples(series string, from, to time.Time) ([]Sample, error) {
+	var samples []Sample
+	err := s.readLines(series, samplesFile, from, func(line []byte) error {
+		var sample Sample
+		if err := json.Unmarshal(line, &sample); err != nil {
+			return err
+		}
+		if !sample.Time.Before(from) && (to.IsZero() || sample.Time.Before(to)) {
+			samples = append(samples, sample)
+		}
+		return nil
+	})
+	return samples, err
+}
+
+// Series lists the series stored so far
+func (s *Store) Series() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var series []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			series = append(series, entry.Name())
+		}
+	}
+	sort.Strings(series)
+	return series, nil
+}
+
+// ResolutionFor returns the name of the resolution matching a candle period
+func ResolutionFor(period time.Duration) (string, bool) {
+	for name, resolution := range Resolutions {
+		if resolution == period {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// SeriesName converts a pair name such as "SOL/USDC" into a series name usable as a directory
+func SeriesName(pair string) string {
+	return strings.ReplaceAll(pair, "/", "-")
+}
+
+// candleFile returns the file name for a candle resolution
+func candleFile(resolution string) string {
+	return "candles-" + resolution + ".jsonl"
+}
+
+// appendLine appends a JSON encoded value to a series file. The caller must hold s.mu.
+func (s *Store) appendLine(series, name string, v interface{}) error {
+	path := filepath.Join(s.dir, series, name)
+	file, ok := s.files[path]
+	if !ok {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create series directory: %v", err)
+		}
+		var err error
+		file, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %v", path, err)
+		}
+		s.files[path] = file
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s: %v", path, err)
+	}
+	return nil
+}
+
+// readLines calls fn for every line of a series file, starting at the first line at or
+// after since (zero for all). A missing file has no lines.
+func (s *Store) readLines(series, name string, since time.Time, fn func(line []byte) error) error {
+	path := filepath.Join(s.dir, series, name)
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	if !since.IsZero() {
+		offset, err := seekTime(file, since)
+		if err != nil {
+			return fmt.Errorf("failed to seek %s: %v", path, err)
+		}
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// compact drops the raw samples of a series recorded before cutoff by rewriting the file
+// from the first sample to keep. The caller must hold s.mu.
+func (s *Store) compact(series string, cutoff time.Time) error {
+	path := filepath.Join(s.dir, series, samplesFile)
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	offset, err := seekTime(file, cutoff)
+	if err != nil {
+		return fmt.Errorf("failed to seek %s: %v", path, err)
+	}
+	if offset == 0 {
+		return nil
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), samplesFile+".*")
+	if err != nil {
+		return fmt.Errorf("failed to compact %s: %v", path, err)
+	}
+	if _, err := io.Copy(tmp, file); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to compact %s: %v", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to compact %s: %v", path, err)
+	}
+
+	// The next sample is appended to the compacted file
+	if appending, ok := s.files[path]; ok {
+		appending.Close()
+		delete(s.files, path)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to compact %s: %v", path, err)
+	}
+	return nil
+}
+
+// seekTime returns the offset of the first line of a time-ordered series file at or after t,
+// or the file size when there is none. It binary searches the file instead of reading it.
+func seekTime(file *os.File, t time.Time) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+
+	// Find the lowest offset whose next line is at or after t
+	low, high := int64(0), size
+	for low < high {
+		mid := low + (high-low)/2
+		start, line, err := nextLine(file, size, mid)
+		if err != nil {
+			return 0, err
+		}
+		if line == nil {
+			high = mid
+			continue
+		}
+
+		var entry struct {
+			Time time.Time `json:"time"`
+		}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return 0, err
+		}
+		if entry.Time.Before(t) {
+			low = start + 1
+		} else {
+			high = mid
+		}
+	}
+
+	start, _, err := nextLine(file, size, low)
+	return start, err
+}
+
+// nextLine returns the first non-empty line starting at or after offset and where it starts.
+// The line is nil when there is none.
+func nextLine(file io.ReaderAt, size int64, offset int64) (int64, []byte, error) {
+	start := offset
+	if offset > 0 {
+		// A line starts at offset only if the previous byte ends a line
+		start = offset - 1
+	}
+	reader := bufio.NewReader(io.NewSectionReader(file, start, size-start))
+	if offset > 0 {
+		skipped, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return size, nil, nil
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		start += int64(len(skipped))
+	}
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			return start, trimmed, nil
+		}
+		if err == io.EOF {
+			return size, nil, nil
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		start += int64(len(line))
+	}
+}
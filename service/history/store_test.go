@@ -0,0 +1,146 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// recordMinutes records one sample a minute for n minutes from start
+func recordMinutes(t *testing.T, store *Store, start time.Time, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := store.Record("SOL-USDC", start.Add(time.Duration(i)*time.Minute), 100+float64(i)); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+}
+
+func TestSeekTime(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "samples.jsonl")
+	lines := `{"time":"2025-01-01T00:00:00Z","price":1}
+{"time":"2025-01-01T00:01:00Z","price":2}
+
+{"time":"2025-01-01T00:02:00Z","price":3}
+{"time":"2025-01-01T00:02:00Z","price":4}
+{"time":"2025-01-01T00:03:00Z","price":5}
+`
+	if err := os.WriteFile(path, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		since time.Time
+		want  string // First line read from the offset
+	}{
+		{"before the first line", base.Add(-time.Hour), `{"time":"2025-01-01T00:00:00Z","price":1}`},
+		{"first line", base, `{"time":"2025-01-01T00:00:00Z","price":1}`},
+		{"between lines", base.Add(30 * time.Second), `{"time":"2025-01-01T00:01:00Z","price":2}`},
+		{"across an empty line", base.Add(90 * time.Second), `{"time":"2025-01-01T00:02:00Z","price":3}`},
+		{"first of equal times", base.Add(2 * time.Minute), `{"time":"2025-01-01T00:02:00Z","price":3}`},
+		{"last line", base.Add(3 * time.Minute), `{"time":"2025-01-01T00:03:00Z","price":5}`},
+		{"after the last line", base.Add(time.Hour), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offset, err := seekTime(file, tt.since)
+			if err != nil {
+				t.Fatalf("seekTime: %v", err)
+			}
+			_, line, err := nextLine(file, int64(len(lines)), offset)
+			if err != nil {
+				t.Fatalf("nextLine: %v", err)
+			}
+			if string(line) != tt.want {
+				t.Errorf("line at offset %d = %q, want %q", offset, line, tt.want)
+			}
+		})
+	}
+}
+
+func TestCandlesSince(t *testing.T) {
+	store, err := Open(t.TempDir(), Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	recordMinutes(t, store, start, 120)
+
+	candles, err := store.Candles("SOL-USDC", "1m", start.Add(100*time.Minute), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The candle of the last sample is still open
+	if len(candles) != 19 || !candles[0].Time.Equal(start.Add(100*time.Minute)) {
+		t.Fatalf("got %d candles from %v, want 19 from 01:40", len(candles), candles[0].Time)
+	}
+
+	candles, err = store.Candles("SOL-USDC", "1m", time.Time{}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candles) != 5 || candles[4].Close != 218 {
+		t.Errorf("got %d candles ending at %v, want the 5 most recent ending at 218", len(candles), candles[len(candles)-1].Close)
+	}
+}
+
+func TestSampleRetention(t *testing.T) {
+	store, err := Open(t.TempDir(), Config{SampleRetention: 2 * 24 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	// Three days of samples, one every 10 minutes
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3*24*6; i++ {
+		if err := store.Record("SOL-USDC", start.Add(time.Duration(i)*10*time.Minute), 100); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	// Compacting runs once a day, and nothing was older than two days yet
+	samples, err := store.Samples("SOL-USDC", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 3*24*6 {
+		t.Fatalf("kept %d samples, want all %d", len(samples), 3*24*6)
+	}
+
+	// A day after the last compaction, the first day's samples are dropped
+	if err := store.Record("SOL-USDC", start.Add(72*time.Hour), 100); err != nil {
+		t.Fatal(err)
+	}
+	samples, err = store.Samples("SOL-USDC", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 2*24*6+1 || !samples[0].Time.Equal(start.Add(24*time.Hour)) {
+		t.Fatalf("kept %d samples from %v, want %d from the second day", len(samples), samples[0].Time, 2*24*6+1)
+	}
+
+	// Samples keep being appended after compacting, and the candles are untouched
+	if err := store.Record("SOL-USDC", start.Add(72*time.Hour+time.Minute), 100); err != nil {
+		t.Fatal(err)
+	}
+	samples, _ = store.Samples("SOL-USDC", start.Add(72*time.Hour), time.Time{})
+	if len(samples) != 2 {
+		t.Errorf("got %d samples since compacting, want 2", len(samples))
+	}
+	candles, _ := store.Candles("SOL-USDC", "1h", time.Time{}, 0)
+	if len(candles) != 72 {
+		t.Errorf("got %d hourly candles, want 72", len(candles))
+	}
+}
@@ -3,16 +3,26 @@ package jupiter
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
-	"path/filepath"
+	"strconv"
 	"swap/internal/datatypes"
 	"swap/pkg/logger"
+	"swap/pkg/metrics"
 	"time"
 
 	"github.com/ilkamo/jupiter-go/jupiter"
 	"github.com/ilkamo/jupiter-go/solana"
 )
 
+// Confirmation polling. Transactions are only considered confirmed once finalized, which
+// usually takes 15-30 seconds; blockhashes expire after about 60-90 seconds.
+const (
+	confirmationPollInterval = 2 * time.Second
+	confirmationTimeout      = 2 * time.Minute
+)
+
 // Service handles Jupiter API interactions
 // Updated to use the new jupiter-go client
 type Service struct {
@@ -20,6 +30,34 @@ type Service struct {
 	config *datatypes.Config
 }
 
+// SwapMode selects which side of a swap the amount refers to
+type SwapMode string
+
+const (
+	// ExactIn spends exactly the given amount of the input token
+	ExactIn SwapMode = "ExactIn"
+
+	// ExactOut receives exactly the given amount of the output token.
+	// Slippage is applied to the input side in this mode.
+	ExactOut SwapMode = "ExactOut"
+)
+
+// AutoPriorityFeeCapLamports is the most Jupiter charges as priority fee when it picks the fee itself
+const AutoPriorityFeeCapLamports = 5_000_000
+
+// SwapParams describes a single swap through Jupiter
+type SwapParams struct {
+	InputMint   string
+	OutputMint  string
+	Amount      uint64 // Input amount for ExactIn, output amount for ExactOut (smallest unit)
+	SlippageBps int
+	Mode        SwapMode // Defaults to ExactIn when empty
+
+	// ComputeUnitPrice is the priority fee in micro-lamports per compute unit. When zero,
+	// Jupiter picks the fee ("auto"), capped at AutoPriorityFeeCapLamports.
+	ComputeUnitPrice uint64
+}
+
 // NewService creates a new Jupiter service
 func NewService(client *jupiter.ClientWithResponses, config *datatypes.Config) *Service {
 	return &Service{
@@ -28,7 +66,35 @@ func NewService(client *jupiter.ClientWithResponses, config *datatypes.Config) *
 	}
 }
 
-// Swap performs a token swap through Jupiter API
+// GetQuote fetches a Jupiter quote for the given swap without executing it
+func (s *Service) GetQuote(ctx context.Context, params SwapParams) (*jupiter.QuoteResponse, error) {
+	mode := params.Mode
+	if mode == "" {
+		mode = ExactIn
+	}
+	quoteMode := jupiter.GetQuoteParamsSwapMode(mode)
+	slippageBps := params.SlippageBps
+
+	start := time.Now()
+	quoteResponse, err := s.client.GetQuoteWithResponse(ctx, &jupiter.GetQuoteParams{
+		InputMint:   params.InputMint,
+		OutputMint:  params.OutputMint,
+		Amount:      jupiter.AmountParameter(params.Amount),
+		SlippageBps: &slippageBps,
+		SwapMode:    &quoteMode,
+	})
+	metrics.QuoteLatency.ObserveSince(start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quote: %v", err)
+	}
+	if quoteResponse.JSON200 == nil {
+		return nil, fmt.Errorf("invalid GetQuoteWithResponse response: %s", quoteResponse.Status())
+	}
+
+	return quoteResponse.JSON200, nil
+}
+
+// Swap performs an ExactIn token swap through Jupiter API
 func (s *Service) Swap(
 	ctx context.Context,
 	inputMint string,
@@ -36,22 +102,74 @@ func (s *Service) Swap(
 	amount uint64,
 	slippageBps int,
 ) error {
-	// Create a channel to communicate the result
+	_, err := s.SwapWithParams(ctx, SwapParams{
+		InputMint:   inputMint,
+		OutputMint:  outputMint,
+		Amount:      amount,
+		SlippageBps: slippageBps,
+		Mode:        ExactIn,
+	})
+	return err
+}
+
+// SwapWithParams performs a token swap through Jupiter API in either ExactIn or ExactOut mode
+// and returns the signature of the confirmed transaction
+func (s *Service) SwapWithParams(ctx context.Context, params SwapParams) (string, error) {
+	inputMint := params.InputMint
+	outputMint := params.OutputMint
+	amount := params.Amount
+	slippageBps := params.SlippageBps
+
+	mode := params.Mode
+	if mode == "" {
+		mode = ExactIn
+	}
+	if mode != ExactIn && mode != ExactOut {
+		return "", fmt.Errorf("unsupported swap mode: %s", mode)
+	}
+	quoteMode := jupiter.GetQuoteParamsSwapMode(mode)
+
+	// Create a channel to communicate the result. The signature is only
+	// read after the result has been received.
 	errChan := make(chan error, 1)
+	var signature string
 
-	// Log file path
-	swapLogPath := filepath.Join("logs", "swap.txt")
+	// Every entry of this swap carries its ID so attempts, retries and confirmations can be correlated
+	swapID := newSwapID()
+	log := logger.With(logger.Fields{"swap_id": swapID, "input_mint": inputMint, "output_mint": outputMint, "amount": amount})
 
-	// Log the swap attempt asynchronously
-	logger.LogSwapAttemptAsync(inputMint, outputMint, amount, slippageBps, swapLogPath)
+	// Record the attempt in the swap audit log
+	audit := logger.SwapRecord{
+		SwapID:      swapID,
+		InputMint:   inputMint,
+		OutputMint:  outputMint,
+		Amount:      amount,
+		SlippageBps: slippageBps,
+		Mode:        string(mode),
+	}
+	auditStatus := func(status string) {
+		record := audit
+		record.Status = status
+		logger.RecordSwap(record)
+
+		switch status {
+		case logger.SwapSuccess:
+			metrics.Swaps.Inc("success")
+		case logger.SwapFailed:
+			metrics.Swaps.Inc("failed")
+			metrics.SwapFailures.Inc(logger.ErrorClass(record.Error))
+		}
+	}
+	auditStatus(logger.SwapAttempt)
 
 	// Use a goroutine with defer/recover to catch any panics
 	go func() {
 		// Defer a recover function to catch any panics
 		defer func() {
 			if r := recover(); r != nil {
-				// Log the failure asynchronously
-				logger.LogSwapFailureAsync(inputMint, outputMint, amount, slippageBps, fmt.Sprintf("Panic: %v", r), swapLogPath)
+				// Record the failure durably before reporting it
+				audit.Error = fmt.Sprint(r)
+				auditStatus(logger.SwapFailed)
 				// Convert the panic to an error and send it through the channel
 				errChan <- fmt.Errorf("swap operation failed: %v", r)
 			}
@@ -59,112 +177,161 @@ func (s *Service) Swap(
 
 		jupClient, err := jupiter.NewClientWithResponses(jupiter.DefaultAPIURL)
 		if err != nil {
-			logger.Error("Failed to create Jupiter client: %v", err)
+			log.Error("Failed to create Jupiter client: %v", err)
 			panic(err)
 		}
 
 		// Get the current quote for a swap.
 		// Ensure that the input and output mints are valid.
 		// The amount is the smallest unit of the input token.
-		logger.Debug("Getting quote for swap: input=%s, output=%s, amount=%d", inputMint, outputMint, amount)
+		log.Debug("Getting quote for swap: input=%s, output=%s, amount=%d, mode=%s", inputMint, outputMint, amount, mode)
+		quoteStart := time.Now()
 		quoteResponse, err := jupClient.GetQuoteWithResponse(ctx, &jupiter.GetQuoteParams{
 			InputMint:   inputMint,
 			OutputMint:  outputMint,
 			Amount:      jupiter.AmountParameter(amount),
 			SlippageBps: &slippageBps,
+			SwapMode:    &quoteMode,
 		})
+		metrics.QuoteLatency.ObserveSince(quoteStart)
 		if err != nil {
-			logger.Error("Failed to get quote: %v", err)
+			log.Error("Failed to get quote: %v", err)
 			panic(err)
 		}
 
 		if quoteResponse.JSON200 == nil {
-			logger.Error("Invalid GetQuoteWithResponse response")
+			log.Error("Invalid GetQuoteWithResponse response")
 			panic("invalid GetQuoteWithResponse response")
 		}
 
 		quote := quoteResponse.JSON200
-		logger.Info("Quote received: inAmount=%v, outAmount=%v",
+		log.Info("Quote received: inAmount=%v, outAmount=%v",
 			quote.InAmount, quote.OutAmount)
 
 		// More info: https://station.jup.ag/docs/apis/troubleshooting
-		prioritizationFeeLamports := jupiter.SwapRequest_PrioritizationFeeLamports{}
-		if err = prioritizationFeeLamports.UnmarshalJSON([]byte(`"auto"`)); err != nil {
-			logger.Error("Failed to unmarshal prioritization fee: %v", err)
-			panic(err)
+		dynamicComputeUnitLimit := true
+		swapRequest := jupiter.PostSwapJSONRequestBody{
+			QuoteResponse:           *quote,
+			UserPublicKey:           s.config.PublicKey.String(),
+			DynamicComputeUnitLimit: &dynamicComputeUnitLimit,
+		}
+		if params.ComputeUnitPrice > 0 {
+			// Pay the fee the caller's cost and reserve estimates are based on
+			computeUnitPrice := jupiter.SwapRequest_ComputeUnitPriceMicroLamports{}
+			if err = computeUnitPrice.FromSwapRequestComputeUnitPriceMicroLamports0(int(params.ComputeUnitPrice)); err != nil {
+				log.Error("Failed to set compute unit price: %v", err)
+				panic(err)
+			}
+			swapRequest.ComputeUnitPriceMicroLamports = &computeUnitPrice
+		} else {
+			prioritizationFeeLamports := jupiter.SwapRequest_PrioritizationFeeLamports{}
+			if err = prioritizationFeeLamports.UnmarshalJSON([]byte(`"auto"`)); err != nil {
+				log.Error("Failed to unmarshal prioritization fee: %v", err)
+				panic(err)
+			}
+			swapRequest.PrioritizationFeeLamports = &prioritizationFeeLamports
 		}
 
-		dynamicComputeUnitLimit := true
 		// Get instructions for a swap.
 		// Ensure your public key is valid.
-		logger.Debug("Requesting swap instructions for user: %s", s.config.PublicKey.String())
-		swapResponse, err := jupClient.PostSwapWithResponse(ctx, jupiter.PostSwapJSONRequestBody{
-			PrioritizationFeeLamports: &prioritizationFeeLamports,
-			QuoteResponse:             *quote,
-			UserPublicKey:             s.config.PublicKey.String(),
-			DynamicComputeUnitLimit:   &dynamicComputeUnitLimit,
-		})
+		log.Debug("Requesting swap instructions for user: %s", s.config.PublicKey.String())
+		swapResponse, err := jupClient.PostSwapWithResponse(ctx, swapRequest)
 		if err != nil {
-			logger.Error("Failed to get swap instructions: %v", err)
+			log.Error("Failed to get swap instructions: %v", err)
 			panic(err)
 		}
 
 		if swapResponse.JSON200 == nil {
-			logger.Error("Invalid PostSwapWithResponse response")
+			log.Error("Invalid PostSwapWithResponse response")
 			panic("invalid PostSwapWithResponse response")
 		}
 
 		swap := swapResponse.JSON200
-		logger.Debug("Swap instructions received")
+		log.Debug("Swap instructions received")
 
 		// Create a wallet from private key.
 		wallet, err := solana.NewWalletFromPrivateKeyBase58(s.config.PrivateKey)
 		if err != nil {
-			logger.Error("Failed to create wallet: %v", err)
+			log.Error("Failed to create wallet: %v", err)
 			panic(err)
 		}
 
 		// Create a Solana client. Change the URL to the desired Solana node.
 		solanaClient, err := solana.NewClient(wallet, s.config.RPCEndpoint)
 		if err != nil {
-			logger.Error("Failed to create Solana client: %v", err)
+			log.Error("Failed to create Solana client: %v", err)
 			panic(err)
 		}
 
 		// Sign and send the transaction.
-		logger.Info("Sending transaction to Solana network")
+		log.Info("Sending transaction to Solana network")
 		signedTx, err := solanaClient.SendTransactionOnChain(ctx, swap.SwapTransaction)
+		sentAt := time.Now()
 		if err != nil {
-			logger.Error("Failed to send transaction: %v", err)
+			log.Error("Failed to send transaction: %v", err)
 			panic(err)
 		}
-		logger.Info("Transaction sent with signature: %s", string(signedTx))
-
-		// Wait a bit to let the transaction propagate to the network.
-		// This is just an example and not a best practice.
-		// You could use a ticker or wait until we implement the WebSocket monitoring ;)
-		logger.Debug("Waiting for transaction confirmation...")
-		time.Sleep(20 * time.Second)
-
-		// Get the status of the transaction (pull the status from the blockchain at intervals
-		// until the transaction is confirmed)
-		logger.Debug("Checking transaction status...")
-		_, err = solanaClient.CheckSignature(ctx, signedTx)
-		if err != nil {
-			// Log the failure asynchronously
-			logger.LogSwapFailureAsync(inputMint, outputMint, amount, slippageBps, fmt.Sprintf("Transaction verification failed: %v", err), swapLogPath)
-			logger.Error("Transaction verification failed: %v", err)
-			panic(err)
+		log = log.With(logger.Fields{"signature": string(signedTx)})
+		log.Info("Transaction sent with signature: %s", string(signedTx))
+		audit.Signature = string(signedTx)
+		auditStatus(logger.SwapSent)
+
+		// Poll the status until the transaction is finalized, so the confirmation time
+		// reflects when the network confirmed it
+		log.Debug("Waiting for transaction confirmation...")
+		if err := waitForConfirmation(ctx, solanaClient, signedTx); err != nil {
+			log.Error("Transaction verification failed: %v", err)
+			panic(fmt.Sprintf("transaction verification failed: %v", err))
 		}
 
-		logger.Info("Transaction confirmed successfully")
-		// Log the successful swap asynchronously
-		logger.LogSwapSuccessAsync(inputMint, outputMint, amount, slippageBps, string(signedTx), swapLogPath)
+		metrics.ConfirmationTime.ObserveSince(sentAt)
+		log.Info("Transaction confirmed successfully")
+		auditStatus(logger.SwapSuccess)
 
 		// If we reach here, the operation was successful
+		signature = string(signedTx)
 		errChan <- nil
 	}()
 
 	// Wait for the result from the goroutine
-	return <-errChan
+	if err := <-errChan; err != nil {
+		return "", err
+	}
+	return signature, nil
+}
+
+// waitForConfirmation polls the signature status until the transaction is finalized.
+// It fails as soon as the transaction is confirmed with an error, or once the timeout
+// passes with the last status error.
+func waitForConfirmation(ctx context.Context, client solana.Client, tx solana.TxID) error {
+	ctx, cancel := context.WithTimeout(ctx, confirmationTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(confirmationPollInterval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("not confirmed (%v), last status: %v", ctx.Err(), lastErr)
+		case <-ticker.C:
+		}
+
+		confirmed, err := client.CheckSignature(ctx, tx)
+		if confirmed {
+			return err
+		}
+		lastErr = err
+		logger.Debug("Transaction %s not confirmed yet: %v", tx, err)
+	}
+}
+
+// newSwapID returns a short random ID identifying one swap in the logs
+func newSwapID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(id)
 }
@@ -0,0 +1,112 @@
+package ledger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"swap/internal/utils"
+)
+
+// benchmarkSaveInterval limits how often equity marks are written to disk
+const benchmarkSaveInterval = time.Minute
+
+// Benchmark compares the bot against buying and holding the risk asset with the
+// whole portfolio from the moment the bot started trading the pair
+type Benchmark struct {
+	Pair              string    `json:"pair"`
+	StartTime         time.Time `json:"startTime"`
+	StartPrice        float64   `json:"startPrice"`
+	StartEquity       float64   `json:"startEquity"`
+	StartRiskQuantity float64   `json:"startRiskQuantity"` // Risk asset held at the start
+	LastTime          time.Time `json:"lastTime"`
+	LastPrice         float64   `json:"lastPrice"`
+	LastEquity        float64   `json:"lastEquity"`
+}
+
+// HoldValue returns what the starting equity would be worth at price if it had been
+// converted to the risk asset at the start price and held
+func (b Benchmark) HoldValue(price float64) float64 {
+	if b.StartPrice <= 0 {
+		return 0
+	}
+	return b.StartEquity / b.StartPrice * price
+}
+
+// OpeningLot returns the risk asset held at the start as a lot valued at the start price.
+// The actual cost and acquisition date are unknown, so the lot is marked as estimated.
+func (b Benchmark) OpeningLot() *Lot {
+	return &Lot{
+		Acquired:    b.StartTime,
+		Quantity:    b.StartRiskQuantity,
+		CostPerUnit: b.StartPrice,
+		Basis:       BasisMarketAtStart,
+	}
+}
+
+// benchmarksFile returns the path of the benchmark file next to the ledger
+func (l *Ledger) benchmarksFile() string {
+	return filepath.Join(l.Dir(), "benchmarks.json")
+}
+
+// Mark records the pair's current equity and price. The first mark of a pair starts its benchmark.
+func (l *Ledger) Mark(pair string, now time.Time, price, equity, riskQuantity float64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.benchmarks == nil {
+		if err := l.loadBenchmarksLocked(); err != nil {
+			return err
+		}
+	}
+
+	benchmark, ok := l.benchmarks[pair]
+	if !ok {
+		benchmark = &Benchmark{
+			Pair:              pair,
+			StartTime:         now.UTC(),
+			StartPrice:        price,
+			StartEquity:       equity,
+			StartRiskQuantity: riskQuantity,
+		}
+		l.benchmarks[pair] = benchmark
+	}
+	benchmark.LastTime = now.UTC()
+	benchmark.LastPrice = price
+	benchmark.LastEquity = equity
+
+	if ok && now.Sub(l.benchmarksSaved) < benchmarkSaveInterval {
+		return nil
+	}
+	l.benchmarksSaved = now
+	return utils.WriteJSONFile(l.benchmarksFile(), l.benchmarks)
+}
+
+// Benchmarks returns the benchmarks of all pairs
+func (l *Ledger) Benchmarks() (map[string]Benchmark, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.benchmarks == nil {
+		if err := l.loadBenchmarksLocked(); err != nil {
+			return nil, err
+		}
+	}
+
+	benchmarks := make(map[string]Benchmark, len(l.benchmarks))
+	for pair, benchmark := range l.benchmarks {
+		benchmarks[pair] = *benchmark
+	}
+	return benchmarks, nil
+}
+
+// loadBenchmarksLocked reads the benchmark file. The caller must hold l.mu.
+func (l *Ledger) loadBenchmarksLocked() error {
+	l.benchmarks = make(map[string]*Benchmark)
+	err := utils.ReadJSONFile(l.benchmarksFile(), &l.benchmarks)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package ledger
+
+import (
+	"fmt"
+	"time"
+)
+
+// Cost basis methods
+const (
+	MethodFIFO       = "fifo"
+	MethodAverage    = "average"
+	MethodSpecificID = "specific-id"
+)
+
+// OpeningLotID identifies the opening position in specific-ID lot selections
+const OpeningLotID = "opening"
+
+// Sources of a lot's cost basis. Only trade, user and par bases are exact; the others are
+// estimates that have to be checked before the figures are used for taxes.
+const (
+	BasisTrade         = "trade"           // Cost of a recorded buy
+	BasisUser          = "user"            // Opening position with a basis and acquisition date supplied by the user
+	BasisMarketAtStart = "market_at_start" // Opening position valued at the market price when the bot started
+	BasisUnknown       = "unknown"         // Sold more than was bought without an opening position: zero basis, unknown acquisition
+	BasisPar           = "par"             // Quote asset disposed of at its face value
+)
+
+// Lot is a quantity of the risk asset acquired at a cost
+type Lot struct {
+	Acquired    time.Time `json:"acquired"` // Zero when unknown
+	Quantity    float64   `json:"quantity"`
+	CostPerUnit float64   `json:"costPerUnit"`
+	Signature   string    `json:"signature,omitempty"` // Empty for the opening position
+	Basis       string    `json:"basis,omitempty"`     // Source of the cost basis
+}
+
+// Estimated reports whether the lot's cost basis or acquisition date is an estimate
+func (l Lot) Estimated() bool {
+	return l.Basis == BasisMarketAtStart || l.Basis == BasisUnknown
+}
+
+// Realization is a gain or loss realized by selling (part of) a lot
+type Realization struct {
+	Trade     Trade
+	Lot       Lot // The lot sold from; for average cost, a synthetic lot at the average cost
+	Quantity  float64
+	Proceeds  float64 // Sale proceeds net of fees, in quote units
+	CostBasis float64
+	Gain      float64
+}
+
+// Summary is the cost basis accounting of a pair's trades
+type Summary struct {
+	Pair         string
+	Method       string
+	Trades       int
+	RealizedPnL  float64
+	FeesQuote    float64
+	OpenQuantity float64
+	OpenCost     float64
+	Realizations []Realization
+}
+
+// AverageCost returns the average cost per unit of the open position
+func (s Summary) AverageCost() float64 {
+	if s.OpenQuantity <= 0 {
+		return 0
+	}
+	return s.OpenCost / s.OpenQuantity
+}
+
+// ComputeCostBasis replays trades in time order and realizes gains on every sell using
+// FIFO lots, the running average cost, or specific lots. Fees are added to the cost of buys
+// and deducted from the proceeds of sells. Selling more than was bought draws from the opening
+// lot, e.g. the position the bot started with; without one, the excess has a zero cost basis
+// and an unknown acquisition date, marked with BasisUnknown.
+//
+// With the specific-ID method, selections maps a sell's signature to the signatures of the
+// buys whose lots it sells (OpeningLotID for the opening lot). Any remainder is sold FIFO.
+func ComputeCostBasis(trades []Trade, method string, opening *Lot, selections map[string][]string) (Summary, error) {
+	if method != MethodFIFO && method != MethodAverage && method != MethodSpecificID {
+		return Summary{}, fmt.Errorf("unknown cost basis method %q", method)
+	}
+
+	summary := Summary{Method: method}
+	var lots []Lot
+	if opening != nil && opening.Quantity > 0 {
+		lot := *opening
+		lot.Signature = OpeningLotID
+		lots = append(lots, lot)
+	}
+
+	for _, trade := range trades {
+		summary.Pair = trade.Pair
+		summary.Trades++
+		summary.FeesQuote += trade.FeeQuote
+
+		if trade.Side == SideBuy {
+			if trade.RiskAmount <= 0 {
+				continue
+			}
+			lot := Lot{
+				Acquired:    trade.Time,
+				Quantity:    trade.RiskAmount,
+				CostPerUnit: (trade.QuoteAmount + trade.FeeQuote) / trade.RiskAmount,
+				Signature:   trade.Signature,
+				Basis:       BasisTrade,
+			}
+			if method == MethodAverage {
+				lots = averageLots(append(lots, lot))
+			} else {
+				lots = append(lots, lot)
+			}
+			continue
+		}
+
+		// Sell: consume lots from the front, after moving any selected lots there
+		if method == MethodSpecificID {
+			lots = selectLots(lots, selections[trade.Signature])
+		}
+		remaining := trade.RiskAmount
+		proceedsPerUnit := (trade.QuoteAmount - trade.FeeQuote) / trade.RiskAmount
+		for remaining > 0 {
+			var lot Lot
+			if len(lots) > 0 {
+				lot = lots[0]
+			} else {
+				lot = Lot{Quantity: remaining, Basis: BasisUnknown}
+			}
+
+			quantity := lot.Quantity
+			if quantity > remaining {
+				quantity = remaining
+			}
+			realization := Realization{
+				Trade:     trade,
+				Lot:       lot,
+				Quantity:  quantity,
+				Proceeds:  quantity * proceedsPerUnit,
+				CostBasis: quantity * lot.CostPerUnit,
+			}
+			realization.Gain = realization.Proceeds - realization.CostBasis
+			summary.Realizations = append(summary.Realizations, realization)
+			summary.RealizedPnL += realization.Gain
+
+			remaining -= quantity
+			if len(lots) > 0 {
+				lots[0].Quantity -= quantity
+				if lots[0].Quantity <= 1e-12 {
+					lots = lots[1:]
+				}
+			}
+		}
+	}
+
+	for _, lot := range lots {
+		summary.OpenQuantity += lot.Quantity
+		summary.OpenCost += lot.Quantity * lot.CostPerUnit
+	}
+	return summary, nil
+}
+
+// selectLots moves the lots with the selected signatures to the front, in selection order
+func selectLots(lots []Lot, selected []string) []Lot {
+	if len(selected) == 0 {
+		return lots
+	}
+
+	ordered := make([]Lot, 0, len(lots))
+	used := make(map[int]bool)
+	for _, signature := range selected {
+		for i, lot := range lots {
+			if !used[i] && lot.Signature == signature {
+				ordered = append(ordered, lot)
+				used[i] = true
+			}
+		}
+	}
+	for i, lot := range lots {
+		if !used[i] {
+			ordered = append(ordered, lot)
+		}
+	}
+	return ordered
+}
+
+// averageLots merges lots into a single lot at their average cost, keeping the earliest
+// acquisition time. The average is an estimate when any of the merged lots is.
+func averageLots(lots []Lot) []Lot {
+	if len(lots) < 2 {
+		return lots
+	}
+
+	merged := Lot{Acquired: lots[0].Acquired, Basis: BasisTrade}
+	cost := 0.0
+	for _, lot := range lots {
+		merged.Quantity += lot.Quantity
+		cost += lot.Quantity * lot.CostPerUnit
+		if lot.Estimated() || (lot.Basis == BasisUser && !merged.Estimated()) {
+			merged.Basis = lot.Basis
+		}
+	}
+	if merged.Quantity > 0 {
+		merged.CostPerUnit = cost / merged.Quantity
+	}
+	return []Lot{merged}
+}
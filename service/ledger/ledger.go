@@ -0,0 +1,215 @@
+// package ledger records executed trades and computes cost basis and PnL from them
+package ledger
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Trade sides relative to the risk asset
+const (
+	SideBuy  = "buy"
+	SideSell = "sell"
+)
+
+// Trade sources
+const (
+	SourceBot  = "bot"  // Recorded by the bot when it executed the swap
+	SourceSync = "sync" // Reconstructed from on-chain wallet history
+)
+
+// Trade is an executed swap between a risk asset and a quote asset. Amounts are the
+// actual balance changes of the confirmed transaction, in whole tokens.
+type Trade struct {
+	Signature   string    `json:"signature"`
+	Time        time.Time `json:"time"`
+	Pair        string    `json:"pair"` // e.g. "SOL/USDC"
+	Side        string    `json:"side"`
+	RiskMint    string    `json:"riskMint"`
+	RiskSymbol  string    `json:"riskSymbol"`
+	RiskAmount  float64   `json:"riskAmount"`
+	QuoteMint   string    `json:"quoteMint"`
+	QuoteSymbol string    `json:"quoteSymbol"`
+	QuoteAmount float64   `json:"quoteAmount"`
+	Price       float64   `json:"price"` // Execution price in quote units per risk unit
+	FeeLamports uint64    `json:"feeLamports"`
+	FeeQuote    float64   `json:"feeQuote"` // Network fee valued in quote units
+	Source      string    `json:"source"`
+}
+
+// SoldAsset returns the symbol and amount of the asset given up in the trade
+func (t Trade) SoldAsset() (string, float64) {
+	if t.Side == SideSell {
+		return t.RiskSymbol, t.RiskAmount
+	}
+	return t.QuoteSymbol, t.QuoteAmount
+}
+
+// BoughtAsset returns the symbol and amount of the asset received in the trade
+func (t Trade) BoughtAsset() (string, float64) {
+	if t.Side == SideSell {
+		return t.QuoteSymbol, t.QuoteAmount
+	}
+	return t.RiskSymbol, t.RiskAmount
+}
+
+// Ledger is an append-only JSON lines file of trades, deduplicated by signature.
+// It is safe for concurrent use.
+type Ledger struct {
+	path string
+
+	mu         sync.Mutex
+	trades     []Trade
+	signatures map[string]bool
+
+	benchmarks      map[string]*Benchmark // Loaded lazily
+	benchmarksSaved time.Time
+}
+
+// Open loads the ledger at path, creating its directory if needed
+func Open(path string) (*Ledger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create ledger directory: %v", err)
+	}
+
+	l := &Ledger{
+		path:       path,
+		signatures: make(map[string]bool),
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return l, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var trade Trade
+		if err := json.Unmarshal(scanner.Bytes(), &trade); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", path, err)
+		}
+		l.trades = append(l.trades, trade)
+		l.signatures[trade.Signature] = true
+	}
+	return l, scanner.Err()
+}
+
+// Dir returns the directory the ledger is stored in
+func (l *Ledger) Dir() string {
+	return filepath.Dir(l.path)
+}
+
+// Has reports whether a trade with the signature is already recorded
+func (l *Ledger) Has(signature string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.signatures[signature]
+}
+
+// Append records a trade. It returns false without writing when the signature is already recorded.
+func (l *Ledger) Append(trade Trade) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.signatures[trade.Signature] {
+		return false, nil
+	}
+
+	data, err := json.Marshal(trade)
+	if err != nil {
+		return false, err
+	}
+
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return false, fmt.Errorf("failed to open ledger: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		return false, fmt.Errorf("failed to write ledger: %v", err)
+	}
+	if err := file.Sync(); err != nil {
+		return false, fmt.Errorf("failed to sync ledger: %v", err)
+	}
+
+	l.trades = append(l.trades, trade)
+	l.signatures[trade.Signature] = true
+	return true, nil
+}
+
+// Trades returns all recorded trades ordered by time
+func (l *Ledger) Trades() []Trade {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	trades := append([]Trade(nil), l.trades...)
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].Time.Before(trades[j].Time) })
+	return trades
+}
+
+// PairTrades returns the recorded trades of one pair ordered by time
+func (l *Ledger) PairTrades(pair string) []Trade {
+	var trades []Trade
+	for _, trade := range l.Trades() {
+		if trade.Pair == pair {
+			trades = append(trades, trade)
+		}
+	}
+	return trades
+}
+
+// NewTrade builds a trade from a parsed transaction, using the balance changes of the risk
+// and quote mints. It fails when the transaction didn't swap between the two.
+func NewTrade(parsed *ParsedTransaction, pair, riskMint, riskSymbol, quoteMint, quoteSymbol string) (Trade, error) {
+	risk, okRisk := parsed.Changes[riskMint]
+	quote, okQuote := parsed.Changes[quoteMint]
+	if !okRisk || !okQuote || (risk.Raw > 0) == (quote.Raw > 0) {
+		return Trade{}, fmt.Errorf("transaction %s is not a %s swap", parsed.Signature, pair)
+	}
+
+	trade := Trade{
+		Signature:   parsed.Signature,
+		Time:        parsed.Time,
+		Pair:        pair,
+		Side:        SideBuy,
+		RiskMint:    riskMint,
+		RiskSymbol:  riskSymbol,
+		RiskAmount:  abs(risk.Float()),
+		QuoteMint:   quoteMint,
+		QuoteSymbol: quoteSymbol,
+		QuoteAmount: abs(quote.Float()),
+		FeeLamports: parsed.FeeLamports,
+	}
+	if risk.Raw < 0 {
+		trade.Side = SideSell
+	}
+	if trade.Time.IsZero() {
+		trade.Time = time.Now().UTC()
+	}
+	if trade.RiskAmount > 0 {
+		trade.Price = trade.QuoteAmount / trade.RiskAmount
+	}
+	return trade, nil
+}
+
+// abs returns the absolute value of x
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
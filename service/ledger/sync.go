@@ -0,0 +1,166 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// signaturePageSize is the maximum number of signatures getSignaturesForAddress returns per call
+const signaturePageSize = 1000
+
+// JupiterProgramIDs are the Jupiter aggregator programs that identify a swap transaction
+var JupiterProgramIDs = []solana.PublicKey{
+	solana.MustPublicKeyFromBase58("JUP6LkbZbjS1jKKwapdHNy74zcZ3tQUJSvLHRvFV4Hz"), // v6
+	solana.MustPublicKeyFromBase58("JUP4Fb2cqiRUcaTHdrPC8h2gNsA2ETXiPDD33WcGuJB"), // v4
+}
+
+// RPC is the subset of the Solana RPC client used to reconstruct trades. *rpc.Client
+// implements it; recorded fixtures can be replayed through any other implementation.
+type RPC interface {
+	GetSignaturesForAddressWithOpts(ctx context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error)
+	GetTransaction(ctx context.Context, signature solana.Signature, opts *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error)
+}
+
+// SyncPair is a pair whose swaps are reconstructed from the wallet history. A trade is
+// recorded under Name for a swap between the risk mint and any of the quote mints.
+type SyncPair struct {
+	Name       string
+	RiskMint   string
+	RiskSymbol string
+	Quotes     map[string]string // Quote symbols keyed by mint
+}
+
+// SyncOptions bound the part of the wallet history that is walked
+type SyncOptions struct {
+	Limit  int              // Maximum number of signatures to scan, newest first (0 scans everything)
+	Before solana.Signature // Start scanning before this signature instead of at the newest one
+	Until  solana.Signature // Stop scanning at this signature
+}
+
+// SyncResult counts what a sync found
+type SyncResult struct {
+	Scanned   int // Signatures looked at
+	Recorded  int // Swaps already in the ledger
+	Failed    int // Transactions that failed on chain
+	NotSwaps  int // Transactions that didn't invoke Jupiter or didn't trade a configured pair
+	Added     int // Swaps appended to the ledger
+	LastError error
+}
+
+// IsJupiterSwap reports whether a transaction invoked a Jupiter program at the top level
+func IsJupiterSwap(parsed *ParsedTransaction) bool {
+	for _, program := range parsed.Programs {
+		for _, jupiter := range JupiterProgramIDs {
+			if program.Equals(jupiter) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Sync walks the owner's transaction history from newest to oldest, identifies Jupiter swaps
+// of the given pairs and appends the ones missing from the ledger. Transactions that can't be
+// loaded are counted and skipped so a single bad record doesn't stop the sync.
+func (l *Ledger) Sync(ctx context.Context, client RPC, owner solana.PublicKey, pairs []SyncPair, opts SyncOptions) (SyncResult, error) {
+	var result SyncResult
+	before := opts.Before
+
+	for opts.Limit <= 0 || result.Scanned < opts.Limit {
+		pageSize := signaturePageSize
+		if opts.Limit > 0 && opts.Limit-result.Scanned < pageSize {
+			pageSize = opts.Limit - result.Scanned
+		}
+
+		signatures, err := client.GetSignaturesForAddressWithOpts(ctx, owner, &rpc.GetSignaturesForAddressOpts{
+			Limit:      &pageSize,
+			Before:     before,
+			Until:      opts.Until,
+			Commitment: rpc.CommitmentFinalized,
+		})
+		if err != nil {
+			return result, fmt.Errorf("failed to get signatures for %s: %v", owner, err)
+		}
+		if len(signatures) == 0 {
+			break
+		}
+
+		for _, entry := range signatures {
+			result.Scanned++
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+			if entry.Err != nil {
+				result.Failed++
+				continue
+			}
+			if l.Has(entry.Signature.String()) {
+				result.Recorded++
+				continue
+			}
+
+			parsed, err := FetchTransaction(ctx, client, entry.Signature, owner)
+			if err != nil {
+				result.LastError = err
+				result.Failed++
+				continue
+			}
+
+			trade, ok := matchTrade(parsed, pairs)
+			if !ok {
+				result.NotSwaps++
+				continue
+			}
+			added, err := l.Append(trade)
+			if err != nil {
+				return result, err
+			}
+			if added {
+				result.Added++
+			} else {
+				result.Recorded++
+			}
+		}
+
+		before = signatures[len(signatures)-1].Signature
+	}
+
+	return result, nil
+}
+
+// matchTrade builds a trade from a Jupiter swap of one of the pairs
+func matchTrade(parsed *ParsedTransaction, pairs []SyncPair) (Trade, bool) {
+	if !IsJupiterSwap(parsed) {
+		return Trade{}, false
+	}
+
+	for _, pair := range pairs {
+		for quoteMint, quoteSymbol := range pair.Quotes {
+			trade, err := NewTrade(parsed, pair.Name, pair.RiskMint, pair.RiskSymbol, quoteMint, quoteSymbol)
+			if err != nil {
+				continue
+			}
+			trade.Source = SourceSync
+			trade.FeeQuote = feeInQuote(trade)
+			return trade, true
+		}
+	}
+	return Trade{}, false
+}
+
+// feeInQuote values the network fee in quote units when one side of the trade is SOL.
+// Other pairs have no SOL price in the transaction and leave the fee unvalued.
+func feeInQuote(trade Trade) float64 {
+	fee := float64(trade.FeeLamports) / 1e9
+	switch wrappedSOLMint {
+	case trade.RiskMint:
+		return fee * trade.Price
+	case trade.QuoteMint:
+		return fee
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,266 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// Signatures of the recorded fixtures in testdata/sync, newest first
+const (
+	sigBonkSwap   = "12pftk3Lp25EXLE7yvacEQRCmG3KY4BXE3dsnm4bq8sUPrR1gFqMxsQ9pWYBbqWV5gKHUjL1KRXJm4pgRsQGoYp"
+	sigFailedSwap = "b7Aqt8UkZTdURnbzcc8squjqbEfvf8YXrdpTZEmyGsaxJMkp9xT8tvMNgVvohjY3WUEEYkzKMJgHZzMLEhCn6Bj"
+	sigTransfer   = "oLX1MjG9Bop29YbuKZudVEDvEbp8bXD55y2HN43iPjTLFvY1APSRMqaBuCtrFigWZi9LfXBHAj9xdojzfdAKvV4"
+	sigBuyV4      = "5eL5wKDTZtrfS8xMqB96ABFRXwCbQRwk4YVF48hCVGBHZ8SPF6QqTSeZ2x51hCkDWF2dC1eP1bxeZEAFMb1UAbPc"
+	sigSellV6     = "4dvpztCGF2hd4Q6yoYoZqnaSo2qnnZDndhBQKhD4cWuSQFpv1yegLw1FvTYxgz83Tf8pSnfQKtZy6uvdzbbXnaaC"
+
+	usdcMint = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+)
+
+var fixtureOwner = solana.MustPublicKeyFromBase58("7xKXtg2CW87d97TXJSDpbD5jBkheTqA83TZRuJosgAsU")
+
+// fixtureRPC replays recorded getSignaturesForAddress and getTransaction responses
+type fixtureRPC struct {
+	dir        string
+	signatures []*rpc.TransactionSignature
+	maxPage    int // Caps every page below the requested limit to force pagination
+
+	signatureCalls   int
+	transactionCalls map[string]int
+}
+
+// newFixtureRPC loads the recorded signatures of dir
+func newFixtureRPC(t *testing.T, dir string, maxPage int) *fixtureRPC {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, "signatures.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fixtureRPC{dir: dir, maxPage: maxPage, transactionCalls: make(map[string]int)}
+	if err := json.Unmarshal(data, &f.signatures); err != nil {
+		t.Fatalf("failed to parse signatures: %v", err)
+	}
+	return f
+}
+
+func (f *fixtureRPC) GetSignaturesForAddressWithOpts(_ context.Context, account solana.PublicKey, opts *rpc.GetSignaturesForAddressOpts) ([]*rpc.TransactionSignature, error) {
+	f.signatureCalls++
+	if !account.Equals(fixtureOwner) {
+		return nil, nil
+	}
+
+	start := 0
+	if !opts.Before.IsZero() {
+		start = len(f.signatures)
+		for i, entry := range f.signatures {
+			if entry.Signature == opts.Before {
+				start = i + 1
+				break
+			}
+		}
+	}
+	limit := signaturePageSize
+	if opts.Limit != nil {
+		limit = *opts.Limit
+	}
+	if f.maxPage > 0 && f.maxPage < limit {
+		limit = f.maxPage
+	}
+
+	var page []*rpc.TransactionSignature
+	for _, entry := range f.signatures[start:] {
+		if len(page) == limit || (!opts.Until.IsZero() && entry.Signature == opts.Until) {
+			break
+		}
+		page = append(page, entry)
+	}
+	return page, nil
+}
+
+func (f *fixtureRPC) GetTransaction(_ context.Context, signature solana.Signature, _ *rpc.GetTransactionOpts) (*rpc.GetTransactionResult, error) {
+	f.transactionCalls[signature.String()]++
+	data, err := os.ReadFile(filepath.Join(f.dir, "transactions", signature.String()+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("not found")
+	}
+	var result rpc.GetTransactionResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// solUSDC is the pair synced in the tests
+var solUSDC = []SyncPair{{
+	Name:       "SOL/USDC",
+	RiskMint:   wrappedSOLMint,
+	RiskSymbol: "SOL",
+	Quotes:     map[string]string{usdcMint: "USDC"},
+}}
+
+func openTestLedger(t *testing.T) *Ledger {
+	t.Helper()
+	l, err := Open(filepath.Join(t.TempDir(), "ledger.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestSyncReconstructsJupiterSwaps(t *testing.T) {
+	client := newFixtureRPC(t, "testdata/sync", 2)
+	l := openTestLedger(t)
+
+	result, err := l.Sync(context.Background(), client, fixtureOwner, solUSDC, SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := SyncResult{Scanned: 5, Failed: 1, NotSwaps: 2, Added: 2}
+	if result != want {
+		t.Errorf("result = %+v, want %+v", result, want)
+	}
+	// Pages of 2, 2 and 1 signatures, then an empty page ends the walk
+	if client.signatureCalls != 4 {
+		t.Errorf("signature pages requested = %d, want 4", client.signatureCalls)
+	}
+	if client.transactionCalls[sigFailedSwap] != 0 {
+		t.Error("fetched a transaction that failed on chain")
+	}
+
+	trades := l.Trades()
+	if len(trades) != 2 {
+		t.Fatalf("got %d trades, want 2", len(trades))
+	}
+	sell, buy := trades[0], trades[1]
+
+	if sell.Signature != sigSellV6 || sell.Side != SideSell || sell.RiskAmount != 1 || sell.QuoteAmount != 150 || sell.Price != 150 {
+		t.Errorf("sell = %+v", sell)
+	}
+	if buy.Signature != sigBuyV4 || buy.Side != SideBuy || buy.RiskAmount != 0.5 || buy.QuoteAmount != 100 || buy.Price != 200 {
+		t.Errorf("buy = %+v", buy)
+	}
+	for _, trade := range trades {
+		if trade.Pair != "SOL/USDC" || trade.Source != SourceSync || trade.FeeLamports != 5000 {
+			t.Errorf("trade %s: pair %q, source %q, fee %d", trade.Signature, trade.Pair, trade.Source, trade.FeeLamports)
+		}
+	}
+	if math.Abs(sell.FeeQuote-0.00075) > 1e-12 {
+		t.Errorf("sell fee in quote = %v, want 0.00075", sell.FeeQuote)
+	}
+	if !sell.Time.Equal(time.Unix(1735761600, 0)) {
+		t.Errorf("sell time = %s", sell.Time)
+	}
+}
+
+func TestSyncDoesNotDuplicateTrades(t *testing.T) {
+	client := newFixtureRPC(t, "testdata/sync", 0)
+	l := openTestLedger(t)
+
+	if _, err := l.Sync(context.Background(), client, fixtureOwner, solUSDC, SyncOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	result, err := l.Sync(context.Background(), client, fixtureOwner, solUSDC, SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Added != 0 || result.Recorded != 2 {
+		t.Errorf("second sync = %+v, want 0 added and 2 recorded", result)
+	}
+	if client.transactionCalls[sigSellV6] != 1 || client.transactionCalls[sigBuyV4] != 1 {
+		t.Errorf("recorded swaps were fetched again: %v", client.transactionCalls)
+	}
+
+	// Reopening the file must not duplicate trades either
+	reopened, err := Open(l.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trades := reopened.Trades(); len(trades) != 2 {
+		t.Errorf("ledger file holds %d trades, want 2", len(trades))
+	}
+}
+
+func TestSyncOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    SyncOptions
+		scanned int
+		added   int
+	}{
+		{"limit", SyncOptions{Limit: 3}, 3, 0},
+		{"before", SyncOptions{Before: solana.MustSignatureFromBase58(sigTransfer)}, 2, 2},
+		{"until", SyncOptions{Until: solana.MustSignatureFromBase58(sigBuyV4)}, 3, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newFixtureRPC(t, "testdata/sync", 2)
+			result, err := openTestLedger(t).Sync(context.Background(), client, fixtureOwner, solUSDC, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Scanned != tt.scanned || result.Added != tt.added {
+				t.Errorf("scanned %d and added %d, want %d and %d", result.Scanned, result.Added, tt.scanned, tt.added)
+			}
+		})
+	}
+}
+
+func TestMatchTrade(t *testing.T) {
+	client := newFixtureRPC(t, "testdata/sync", 0)
+	parse := func(signature string) *ParsedTransaction {
+		t.Helper()
+		parsed, err := FetchTransaction(context.Background(), client, solana.MustSignatureFromBase58(signature), fixtureOwner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		name      string
+		signature string
+		jupiter   bool
+		matched   bool
+	}{
+		{"v6 swap", sigSellV6, true, true},
+		{"v4 swap", sigBuyV4, true, true},
+		{"transfer", sigTransfer, false, false},
+		{"swap of another pair", sigBonkSwap, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed := parse(tt.signature)
+			if got := IsJupiterSwap(parsed); got != tt.jupiter {
+				t.Errorf("IsJupiterSwap = %v, want %v", got, tt.jupiter)
+			}
+			if _, ok := matchTrade(parsed, solUSDC); ok != tt.matched {
+				t.Errorf("matchTrade = %v, want %v", ok, tt.matched)
+			}
+		})
+	}
+
+	// The same swap matches once its pair is configured
+	bonk := SyncPair{
+		Name:       "BONK/USDC",
+		RiskMint:   "DezXAZ8z7PnrnRJjz3wXBoRgixCa6xjnB7YaB1pPB263",
+		RiskSymbol: "BONK",
+		Quotes:     map[string]string{usdcMint: "USDC"},
+	}
+	trade, ok := matchTrade(parse(sigBonkSwap), append(solUSDC, bonk))
+	if !ok || trade.Pair != "BONK/USDC" || trade.Side != SideBuy || trade.RiskAmount != 500000 || trade.QuoteAmount != 10 {
+		t.Errorf("BONK swap = %+v, %v", trade, ok)
+	}
+	if trade.FeeQuote != 0 {
+		t.Errorf("fee of a pair without SOL valued at %v, want 0", trade.FeeQuote)
+	}
+}
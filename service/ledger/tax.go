@@ -0,0 +1,151 @@
+package ledger
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// TaxRow is one disposal in the tax export. Sells of the risk asset produce one row per
+// lot sold; buys are disposals of the quote asset at par and produce a single row.
+type TaxRow struct {
+	Date         time.Time
+	Pair         string
+	SoldAsset    string
+	SoldAmount   float64
+	BoughtAsset  string
+	BoughtAmount float64
+	ValueUSD     float64
+	FeeUSD       float64
+	Signature    string
+	Acquired     time.Time // Zero for quote asset disposals and lots of unknown age
+	Lot          string    // Signature of the buy the lot came from, or OpeningLotID
+	CostBasis    float64
+	BasisSource  string // One of the Basis constants; estimates must be reviewed
+	Gain         float64
+}
+
+// Estimated reports whether the row's cost basis or acquisition date is an estimate
+func (r TaxRow) Estimated() bool {
+	return r.BasisSource == BasisMarketAtStart || r.BasisSource == BasisUnknown
+}
+
+// TaxRows builds export rows for a pair's trades executed in [from, to) from its cost basis summary
+func TaxRows(trades []Trade, summary Summary, from, to time.Time) []TaxRow {
+	inRange := func(t time.Time) bool {
+		return !t.Before(from) && (to.IsZero() || t.Before(to))
+	}
+
+	var rows []TaxRow
+	for _, trade := range trades {
+		if trade.Side != SideBuy || !inRange(trade.Time) {
+			continue
+		}
+		rows = append(rows, TaxRow{
+			Date:         trade.Time,
+			Pair:         trade.Pair,
+			SoldAsset:    trade.QuoteSymbol,
+			SoldAmount:   trade.QuoteAmount,
+			BoughtAsset:  trade.RiskSymbol,
+			BoughtAmount: trade.RiskAmount,
+			ValueUSD:     trade.QuoteAmount,
+			FeeUSD:       trade.FeeQuote,
+			Signature:    trade.Signature,
+			CostBasis:    trade.QuoteAmount,
+			BasisSource:  BasisPar,
+		})
+	}
+
+	for _, realization := range summary.Realizations {
+		trade := realization.Trade
+		if !inRange(trade.Time) || trade.RiskAmount <= 0 {
+			continue
+		}
+		share := realization.Quantity / trade.RiskAmount
+		rows = append(rows, TaxRow{
+			Date:         trade.Time,
+			Pair:         trade.Pair,
+			SoldAsset:    trade.RiskSymbol,
+			SoldAmount:   realization.Quantity,
+			BoughtAsset:  trade.QuoteSymbol,
+			BoughtAmount: trade.QuoteAmount * share,
+			ValueUSD:     trade.QuoteAmount * share,
+			FeeUSD:       trade.FeeQuote * share,
+			Signature:    trade.Signature,
+			Acquired:     realization.Lot.Acquired,
+			Lot:          realization.Lot.Signature,
+			CostBasis:    realization.CostBasis,
+			BasisSource:  realization.Lot.Basis,
+			Gain:         realization.Gain,
+		})
+	}
+
+	SortTaxRows(rows)
+	return rows
+}
+
+// SortTaxRows orders rows by date, pair, transaction and lot acquisition, so exports
+// of the same ledger are identical
+func SortTaxRows(rows []TaxRow) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		switch {
+		case !a.Date.Equal(b.Date):
+			return a.Date.Before(b.Date)
+		case a.Pair != b.Pair:
+			return a.Pair < b.Pair
+		case a.Signature != b.Signature:
+			return a.Signature < b.Signature
+		case !a.Acquired.Equal(b.Acquired):
+			return a.Acquired.Before(b.Acquired)
+		default:
+			return a.Lot < b.Lot
+		}
+	})
+}
+
+// WriteTaxCSV writes tax rows as CSV with dates in the given location
+func WriteTaxCSV(w io.Writer, rows []TaxRow, loc *time.Location) error {
+	writer := csv.NewWriter(w)
+	header := []string{
+		"date", "sold_asset", "sold_amount", "bought_asset", "bought_amount",
+		"usd_value", "fee_usd", "tx_signature", "acquired_date", "cost_basis_usd", "basis_source", "realized_gain_usd",
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		acquired := ""
+		if !row.Acquired.IsZero() {
+			acquired = row.Acquired.In(loc).Format(time.RFC3339)
+		}
+		record := []string{
+			row.Date.In(loc).Format(time.RFC3339),
+			row.SoldAsset,
+			formatAmount(row.SoldAmount),
+			row.BoughtAsset,
+			formatAmount(row.BoughtAmount),
+			strconv.FormatFloat(row.ValueUSD, 'f', 2, 64),
+			strconv.FormatFloat(row.FeeUSD, 'f', 6, 64),
+			row.Signature,
+			acquired,
+			strconv.FormatFloat(row.CostBasis, 'f', 2, 64),
+			row.BasisSource,
+			strconv.FormatFloat(row.Gain, 'f', 2, 64),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// formatAmount formats a token amount without trailing zeros
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
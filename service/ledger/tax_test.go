@@ -0,0 +1,107 @@
+package ledger
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+)
+
+func TestTaxRowsBasisSource(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	trades := []Trade{
+		{Signature: "buy", Time: start.Add(time.Hour), Pair: "SOL/USDC", Side: SideBuy,
+			RiskSymbol: "SOL", RiskAmount: 1, QuoteSymbol: "USDC", QuoteAmount: 120},
+		{Signature: "sell", Time: start.Add(2 * time.Hour), Pair: "SOL/USDC", Side: SideSell,
+			RiskSymbol: "SOL", RiskAmount: 3, QuoteSymbol: "USDC", QuoteAmount: 450},
+	}
+	opening := Benchmark{StartTime: start, StartRiskQuantity: 1, StartPrice: 100}.OpeningLot()
+
+	summary, err := ComputeCostBasis(trades, MethodFIFO, opening, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := TaxRows(trades, summary, time.Time{}, time.Time{})
+
+	want := []struct {
+		signature string
+		lot       string
+		basis     string
+		acquired  time.Time
+		costBasis float64
+		estimated bool
+	}{
+		{"buy", "", BasisPar, time.Time{}, 120, false},
+		{"sell", "", BasisUnknown, time.Time{}, 0, true},
+		{"sell", OpeningLotID, BasisMarketAtStart, start, 100, true},
+		{"sell", "buy", BasisTrade, start.Add(time.Hour), 120, false},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %+v", len(rows), len(want), rows)
+	}
+	for i, w := range want {
+		row := rows[i]
+		if row.Signature != w.signature || row.Lot != w.lot || row.BasisSource != w.basis ||
+			!row.Acquired.Equal(w.acquired) || row.CostBasis != w.costBasis || row.Estimated() != w.estimated {
+			t.Errorf("row %d = %+v, want %+v", i, row, w)
+		}
+	}
+}
+
+func TestSortTaxRowsIsDeterministic(t *testing.T) {
+	at := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	sorted := []TaxRow{
+		{Date: at.Add(-time.Minute), Pair: "SOL/USDT", Signature: "c"},
+		{Date: at, Pair: "JUP/USDC", Signature: "z"},
+		{Date: at, Pair: "SOL/USDC", Signature: "a", Acquired: at.Add(-2 * time.Hour), Lot: "x"},
+		{Date: at, Pair: "SOL/USDC", Signature: "a", Acquired: at.Add(-time.Hour), Lot: "a"},
+		{Date: at, Pair: "SOL/USDC", Signature: "a", Acquired: at.Add(-time.Hour), Lot: "b"},
+		{Date: at, Pair: "SOL/USDC", Signature: "b"},
+	}
+
+	for _, order := range [][]int{{5, 4, 3, 2, 1, 0}, {2, 0, 5, 1, 4, 3}, {4, 5, 0, 3, 2, 1}} {
+		rows := make([]TaxRow, len(order))
+		for i, j := range order {
+			rows[i] = sorted[j]
+		}
+		SortTaxRows(rows)
+		for i := range rows {
+			if rows[i] != sorted[i] {
+				t.Errorf("order %v: row %d = %+v, want %+v", order, i, rows[i], sorted[i])
+			}
+		}
+	}
+}
+
+func TestWriteTaxCSVBasisSource(t *testing.T) {
+	rows := []TaxRow{{
+		Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Pair: "SOL/USDC",
+		SoldAsset: "SOL", SoldAmount: 1, BoughtAsset: "USDC", BoughtAmount: 150,
+		Signature: "sell", CostBasis: 0, BasisSource: BasisUnknown, Gain: 150,
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteTaxCSV(&buf, rows, time.UTC); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want header and one row", len(records))
+	}
+
+	column := -1
+	for i, name := range records[0] {
+		if name == "basis_source" {
+			column = i
+		}
+	}
+	if column < 0 {
+		t.Fatalf("header %v has no basis_source column", records[0])
+	}
+	if got := records[1][column]; got != BasisUnknown {
+		t.Errorf("basis_source = %q, want %q", got, BasisUnknown)
+	}
+}
@@ -0,0 +1,134 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// wrappedSOLMint is the mint native SOL balance changes are reported under
+const wrappedSOLMint = "So11111111111111111111111111111111111111112"
+
+// BalanceChange is the change of the wallet's balance of one mint in a transaction, in base units
+type BalanceChange struct {
+	Mint     string
+	Raw      int64
+	Decimals uint8
+}
+
+// Float returns the change in whole tokens
+func (c BalanceChange) Float() float64 {
+	return float64(c.Raw) / math.Pow10(int(c.Decimals))
+}
+
+// ParsedTransaction holds the wallet's balance changes in a confirmed transaction
+type ParsedTransaction struct {
+	Signature   string
+	Time        time.Time
+	FeeLamports uint64
+	Changes     map[string]BalanceChange // Keyed by mint; native SOL and wrapped SOL are combined
+	Programs    []solana.PublicKey       // Programs invoked by the top level instructions
+}
+
+// FetchTransaction loads a confirmed transaction and parses the owner's balance changes
+func FetchTransaction(ctx context.Context, client RPC, signature solana.Signature, owner solana.PublicKey) (*ParsedTransaction, error) {
+	maxVersion := uint64(0)
+	result, err := client.GetTransaction(ctx, signature, &rpc.GetTransactionOpts{
+		Encoding:                       solana.EncodingBase64,
+		Commitment:                     rpc.CommitmentConfirmed,
+		MaxSupportedTransactionVersion: &maxVersion,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction %s: %v", signature, err)
+	}
+	return ParseTransaction(signature.String(), result, owner)
+}
+
+// ParseTransaction extracts the owner's balance changes from a transaction result using the
+// pre and post balances recorded in its metadata. Native SOL changes exclude the network fee,
+// which is reported separately, and are merged with wrapped SOL changes.
+func ParseTransaction(signature string, result *rpc.GetTransactionResult, owner solana.PublicKey) (*ParsedTransaction, error) {
+	if result == nil || result.Meta == nil {
+		return nil, fmt.Errorf("transaction %s has no metadata", signature)
+	}
+	meta := result.Meta
+	if meta.Err != nil {
+		return nil, fmt.Errorf("transaction %s failed: %v", signature, meta.Err)
+	}
+
+	parsed := &ParsedTransaction{
+		Signature:   signature,
+		FeeLamports: meta.Fee,
+		Changes:     make(map[string]BalanceChange),
+	}
+	if result.BlockTime != nil {
+		parsed.Time = result.BlockTime.Time().UTC()
+	}
+
+	// The wallet pays the fee, so it is the first account of the transaction
+	var accountKeys []solana.PublicKey
+	if result.Transaction != nil {
+		tx, err := result.Transaction.GetTransaction()
+		if err == nil && tx != nil {
+			accountKeys = tx.Message.AccountKeys
+			for _, instruction := range tx.Message.Instructions {
+				if int(instruction.ProgramIDIndex) < len(accountKeys) {
+					parsed.Programs = append(parsed.Programs, accountKeys[instruction.ProgramIDIndex])
+				}
+			}
+		}
+	}
+	if len(accountKeys) == 0 || accountKeys[0].Equals(owner) {
+		if len(meta.PreBalances) > 0 && len(meta.PostBalances) > 0 {
+			lamports := int64(meta.PostBalances[0]) - int64(meta.PreBalances[0]) + int64(meta.Fee)
+			if lamports != 0 {
+				parsed.add(wrappedSOLMint, lamports, 9)
+			}
+		}
+	}
+
+	// Token balances only list accounts touched by the transaction
+	for _, balance := range meta.PreTokenBalances {
+		if raw, decimals, ok := ownedAmount(balance, owner); ok {
+			parsed.add(balance.Mint.String(), -raw, decimals)
+		}
+	}
+	for _, balance := range meta.PostTokenBalances {
+		if raw, decimals, ok := ownedAmount(balance, owner); ok {
+			parsed.add(balance.Mint.String(), raw, decimals)
+		}
+	}
+
+	for mint, change := range parsed.Changes {
+		if change.Raw == 0 {
+			delete(parsed.Changes, mint)
+		}
+	}
+	return parsed, nil
+}
+
+// add accumulates a balance change for a mint
+func (p *ParsedTransaction) add(mint string, raw int64, decimals uint8) {
+	change := p.Changes[mint]
+	change.Mint = mint
+	change.Raw += raw
+	change.Decimals = decimals
+	p.Changes[mint] = change
+}
+
+// ownedAmount returns the raw amount of a token balance if it belongs to the owner
+func ownedAmount(balance rpc.TokenBalance, owner solana.PublicKey) (int64, uint8, bool) {
+	if balance.Owner == nil || !balance.Owner.Equals(owner) || balance.UiTokenAmount == nil {
+		return 0, 0, false
+	}
+	raw, err := strconv.ParseInt(balance.UiTokenAmount.Amount, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return raw, balance.UiTokenAmount.Decimals, true
+}
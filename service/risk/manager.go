@@ -0,0 +1,227 @@
+// package risk halts trading when loss limits are hit or the kill switch is engaged
+package risk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"swap/internal/utils"
+	"swap/pkg/logger"
+)
+
+// ErrHalted is returned when a swap is blocked because trading is halted
+var ErrHalted = errors.New("trading halted")
+
+// Halt reasons
+const (
+	ReasonDailyLoss  = "daily loss limit"
+	ReasonDrawdown   = "max drawdown"
+	ReasonKillSwitch = "kill switch"
+	ReasonManual     = "manual halt"
+)
+
+// Limits configures the circuit breaker. Zero values disable a limit.
+type Limits struct {
+	DailyLossLimit float64 // Maximum loss in USD since the start of the UTC day
+	MaxDrawdownPct float64 // Maximum drop from peak equity, in percent
+	KillSwitchFile string  // Trading halts while this file exists
+	StateFile      string  // Where halts and equity marks are persisted
+}
+
+// State is the persisted state of the circuit breaker
+type State struct {
+	Halted         bool      `json:"halted"`
+	Reason         string    `json:"reason,omitempty"`
+	HaltedAt       time.Time `json:"haltedAt,omitempty"`
+	Day            string    `json:"day"`
+	DayStartEquity float64   `json:"dayStartEquity"`
+	PeakEquity     float64   `json:"peakEquity"`
+}
+
+// Manager tracks the wallet's equity across all pairs and decides whether trading may continue.
+// Equity changes capture both realized and unrealized PnL, since every side of every pair
+// is valued at the current price. It is safe for concurrent use.
+type Manager struct {
+	limits Limits
+
+	mu     sync.Mutex
+	state  State
+	pairs  map[string]bool    // Registered pairs
+	equity map[string]float64 // Latest equity reported by each pair
+}
+
+// NewManager creates a risk manager, restoring any halt persisted by a previous run
+func NewManager(limits Limits) *Manager {
+	m := &Manager{
+		limits: limits,
+		pairs:  make(map[string]bool),
+		equity: make(map[string]float64),
+	}
+
+	if limits.StateFile != "" {
+		if err := utils.ReadJSONFile(limits.StateFile, &m.state); err != nil && !errors.Is(err, os.ErrNotExist) {
+			logger.Warn("Failed to load risk state from %s: %v", limits.StateFile, err)
+		}
+	}
+	if m.state.Halted {
+		logger.Error("TRADING HALTED since %s: %s. Resume manually to continue trading.",
+			m.state.HaltedAt.Format(time.RFC3339), m.state.Reason)
+	}
+	return m
+}
+
+// Register adds a pair whose equity counts towards the wallet total
+func (m *Manager) Register(pair string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pairs[pair] = true
+}
+
+// Update records the current equity of a pair in USD and halts trading when
+// the combined equity breaches the daily loss or drawdown limit. Limits are only
+// evaluated once every registered pair has reported, so a partial total is never
+// compared against the persisted marks.
+func (m *Manager) Update(pair string, equity float64, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.equity[pair] = equity
+	for registered := range m.pairs {
+		if _, ok := m.equity[registered]; !ok {
+			return
+		}
+	}
+	total := m.totalLocked()
+
+	day := now.UTC().Format("2006-01-02")
+	if m.state.Day != day {
+		// A daily loss halt ends with the day it was triggered on
+		if m.state.Halted && m.state.Reason == ReasonDailyLoss {
+			m.resumeLocked("new trading day")
+		}
+		m.state.Day = day
+		m.state.DayStartEquity = total
+		m.save()
+	}
+	if total > m.state.PeakEquity {
+		m.state.PeakEquity = total
+		m.save()
+	}
+
+	if m.state.Halted {
+		return
+	}
+
+	dailyPnL := total - m.state.DayStartEquity
+	if m.limits.DailyLossLimit > 0 && -dailyPnL >= m.limits.DailyLossLimit {
+		m.haltLocked(ReasonDailyLoss, fmt.Sprintf("down $%.2f today (limit $%.2f)", -dailyPnL, m.limits.DailyLossLimit))
+		return
+	}
+
+	if m.limits.MaxDrawdownPct > 0 && m.state.PeakEquity > 0 {
+		drawdown := (m.state.PeakEquity - total) / m.state.PeakEquity * 100
+		if drawdown >= m.limits.MaxDrawdownPct {
+			m.haltLocked(ReasonDrawdown, fmt.Sprintf("equity $%.2f is %.2f%% below peak $%.2f (limit %.2f%%)",
+				total, drawdown, m.state.PeakEquity, m.limits.MaxDrawdownPct))
+		}
+	}
+}
+
+// Check returns ErrHalted when trading is halted or the kill switch file exists
+func (m *Manager) Check() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.limits.KillSwitchFile != "" {
+		if _, err := os.Stat(m.limits.KillSwitchFile); err == nil {
+			if !m.state.Halted {
+				m.haltLocked(ReasonKillSwitch, "found "+m.limits.KillSwitchFile)
+			}
+		} else if m.state.Halted && m.state.Reason == ReasonKillSwitch {
+			m.resumeLocked("kill switch file removed")
+		}
+	}
+
+	if m.state.Halted {
+		return fmt.Errorf("%w: %s", ErrHalted, m.state.Reason)
+	}
+	return nil
+}
+
+// Halt stops all trading until Resume is called, e.g. from a signal handler
+func (m *Manager) Halt(reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.haltLocked(ReasonManual, reason)
+}
+
+// Resume lifts a halt. The peak is reset to the current equity so a drawdown
+// halt doesn't immediately trigger again.
+func (m *Manager) Resume(reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.resumeLocked(reason)
+}
+
+// Status returns a copy of the current state
+func (m *Manager) Status() State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state
+}
+
+// haltLocked halts trading. The caller must hold m.mu.
+func (m *Manager) haltLocked(reason, detail string) {
+	m.state.Halted = true
+	m.state.Reason = reason
+	m.state.HaltedAt = time.Now().UTC()
+	m.save()
+
+	logger.Error("==================================================")
+	logger.Error("TRADING HALTED: %s (%s)", reason, detail)
+	logger.Error("No swaps will be made until trading is resumed")
+	logger.Error("==================================================")
+}
+
+// resumeLocked resumes trading. The caller must hold m.mu.
+func (m *Manager) resumeLocked(detail string) {
+	if !m.state.Halted {
+		return
+	}
+
+	total := m.totalLocked()
+
+	logger.Warn("==================================================")
+	logger.Warn("TRADING RESUMED after %s halt: %s", m.state.Reason, detail)
+	logger.Warn("==================================================")
+
+	m.state.Halted = false
+	m.state.Reason = ""
+	m.state.HaltedAt = time.Time{}
+	if total > 0 {
+		m.state.PeakEquity = total
+	}
+	m.save()
+}
+
+// totalLocked returns the combined equity of all pairs. The caller must hold m.mu.
+func (m *Manager) totalLocked() float64 {
+	total := 0.0
+	for _, value := range m.equity {
+		total += value
+	}
+	return total
+}
+
+// save persists the state. The caller must hold m.mu.
+func (m *Manager) save() {
+	if m.limits.StateFile == "" {
+		return
+	}
+	if err := utils.WriteJSONFile(m.limits.StateFile, m.state); err != nil {
+		logger.Warn("Failed to save risk state to %s: %v", m.limits.StateFile, err)
+	}
+}
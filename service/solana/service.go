@@ -4,57 +4,92 @@ package solana
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"swap/internal/datatypes"
 	"swap/internal/utils"
 	"swap/pkg/logger"
+	"swap/pkg/metrics"
+	"sync"
+	"time"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 )
 
+// BaseFeeLamports is the network fee charged per transaction signature
+const BaseFeeLamports = 5000
+
+// TokenAccountSize is the data size in bytes of an SPL token account
+const TokenAccountSize = 165
+
+// priorityFeeCacheTTL is how long a priority fee estimate is reused
+const priorityFeeCacheTTL = 30 * time.Second
+
 // Service handles Solana-related operations
 type Service struct {
 	client *rpc.Client
+
+	// Rent only changes with a cluster upgrade, so it is fetched once per account size;
+	// priority fee estimates are reused for priorityFeeCacheTTL
+	cacheMu       sync.Mutex
+	rentExempt    map[uint64]uint64
+	priorityFee   uint64
+	priorityFeeAt time.Time
 }
 
 // NewService creates a new Solana service instance
 func NewService(client *rpc.Client) *Service {
 	return &Service{
-		client: client,
+		client:     client,
+		rentExempt: make(map[uint64]uint64),
+	}
+}
+
+// GetPrice retrieves the current USD price of a token mint from Jupiter API
+func (s *Service) GetPrice(ctx context.Context, mint string) (float64, error) {
+	prices, err := s.GetPrices(ctx, mint)
+	if err != nil {
+		return 0, err
 	}
+	return prices[mint], nil
 }
 
-// GetSOLPrice retrieves the current SOL price from Jupiter API
-func (s *Service) GetSOLPrice(ctx context.Context) (float64, error) {
-	logger.Debug("Fetching SOL price")
-	apiURL := "https://api.jup.ag/price/v2?ids=So11111111111111111111111111111111111111112&showExtraInfo=false"
+// GetPrices retrieves the current USD prices of several token mints in a single Jupiter API call
+func (s *Service) GetPrices(ctx context.Context, mints ...string) (map[string]float64, error) {
+	defer metrics.PriceFetchLatency.ObserveSince(time.Now())
+	logger.Debug("Fetching prices for %s", strings.Join(mints, ","))
+	apiURL := "https://api.jup.ag/price/v2?ids=" + strings.Join(mints, ",") + "&showExtraInfo=false"
 
 	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create request: %v", err)
+		return nil, fmt.Errorf("failed to create request: %v", err)
 	}
 
 	req.Header.Add("accept", "application/json")
 
 	res, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return 0, fmt.Errorf("failed to perform request: %v", err)
+		return nil, fmt.Errorf("failed to perform request: %v", err)
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("received non-200 response: %s", res.Status)
+		return nil, fmt.Errorf("received non-200 response: %s", res.Status)
 	}
 
 	body, err := io.ReadAll(res.Body)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read response body: %v", err)
+		return nil, fmt.Errorf("failed to read response body: %v", err)
 	}
 
 	var response struct {
-		Data map[string]struct {
+		Data map[string]*struct {
 			ID    string `json:"id"`
 			Type  string `json:"type"`
 			Price string `json:"price"`
@@ -63,38 +98,64 @@ func (s *Service) GetSOLPrice(ctx context.Context) (float64, error) {
 	}
 
 	if err := json.Unmarshal(body, &response); err != nil {
-		return 0, fmt.Errorf("failed to parse price data: %v", err)
+		return nil, fmt.Errorf("failed to parse price data: %v", err)
 	}
 
-	solData, exists := response.Data["So11111111111111111111111111111111111111112"]
-	if !exists {
-		return 0, fmt.Errorf("SOL price not found in response")
+	prices := make(map[string]float64, len(mints))
+	for _, mint := range mints {
+		data, exists := response.Data[mint]
+		if !exists || data == nil {
+			return nil, fmt.Errorf("price for %s not found in response", mint)
+		}
+
+		price, err := utils.ParseFloat(data.Price)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse price for %s: %v", mint, err)
+		}
+		prices[mint] = price
 	}
 
-	price, err := utils.ParseFloat(solData.Price)
-	if err != nil {
-		return 0, fmt.Errorf("failed to parse SOL price: %v", err)
-	}
-
-	return price, nil
+	return prices, nil
 }
 
-// CheckSolBalance retrieves the SOL balance for a wallet
-func (s *Service) CheckSolBalance(ctx context.Context, walletAddress solana.PublicKey) (float64, error) {
+// CheckSolBalance retrieves the SOL balance for a wallet in lamports
+func (s *Service) CheckSolBalance(ctx context.Context, walletAddress solana.PublicKey) (datatypes.TokenAmount, error) {
 	balance, err := s.client.GetBalance(
 		ctx,
 		walletAddress,
 		rpc.CommitmentFinalized,
 	)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get SOL balance: %v", err)
+		return datatypes.TokenAmount{}, fmt.Errorf("failed to get SOL balance: %v", err)
+	}
+
+	return datatypes.TokenAmount{Raw: balance.Value, Decimals: 9}, nil
+}
+
+// GetTokenBalance retrieves the balance of a token account in base units.
+// A token account that doesn't exist yet has a zero balance.
+func (s *Service) GetTokenBalance(ctx context.Context, tokenAccount solana.PublicKey, decimals uint8) (datatypes.TokenAmount, error) {
+	balance, err := s.client.GetTokenAccountBalance(ctx, tokenAccount, rpc.CommitmentFinalized)
+	if err != nil {
+		if strings.Contains(err.Error(), "could not find account") {
+			return datatypes.TokenAmount{Decimals: decimals}, nil
+		}
+		return datatypes.TokenAmount{}, fmt.Errorf("failed to get token balance: %v", err)
+	}
+	if balance == nil || balance.Value == nil {
+		return datatypes.TokenAmount{Decimals: decimals}, nil
+	}
+
+	raw, err := strconv.ParseUint(balance.Value.Amount, 10, 64)
+	if err != nil {
+		return datatypes.TokenAmount{}, fmt.Errorf("failed to parse token balance: %v", err)
 	}
 
-	// Convert lamports to SOL
-	return float64(balance.Value) / 1e9, nil
+	return datatypes.TokenAmount{Raw: raw, Decimals: balance.Value.Decimals}, nil
 }
 
-// FindTokenAccount looks up or creates an associated token account for a specific token mint
+// FindTokenAccount looks up the associated token account for a specific token mint.
+// A missing account is reported as not existing; RPC failures are returned as errors.
 func (s *Service) FindTokenAccount(ctx context.Context, walletAddress solana.PublicKey, tokenMint string) (solana.PublicKey, bool, error) {
 	// Parse token mint
 	tokenMintPubkey, err := solana.PublicKeyFromBase58(tokenMint)
@@ -110,7 +171,64 @@ func (s *Service) FindTokenAccount(ctx context.Context, walletAddress solana.Pub
 
 	// Check if the token account exists
 	_, err = s.client.GetAccountInfo(ctx, tokenAccount)
-	exists := err == nil
+	if err != nil {
+		if errors.Is(err, rpc.ErrNotFound) {
+			return tokenAccount, false, nil
+		}
+		return tokenAccount, false, fmt.Errorf("failed to get token account: %v", err)
+	}
+
+	return tokenAccount, true, nil
+}
+
+// GetPriorityFeeEstimate returns the median recent prioritization fee in micro-lamports per compute unit
+func (s *Service) GetPriorityFeeEstimate(ctx context.Context) (uint64, error) {
+	s.cacheMu.Lock()
+	if !s.priorityFeeAt.IsZero() && time.Since(s.priorityFeeAt) < priorityFeeCacheTTL {
+		fee := s.priorityFee
+		s.cacheMu.Unlock()
+		return fee, nil
+	}
+	s.cacheMu.Unlock()
+
+	fees, err := s.client.GetRecentPrioritizationFees(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get recent prioritization fees: %v", err)
+	}
+
+	estimate := uint64(0)
+	if len(fees) > 0 {
+		values := make([]uint64, 0, len(fees))
+		for _, fee := range fees {
+			values = append(values, fee.PrioritizationFee)
+		}
+		sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+		estimate = values[len(values)/2]
+	}
+
+	s.cacheMu.Lock()
+	s.priorityFee = estimate
+	s.priorityFeeAt = time.Now()
+	s.cacheMu.Unlock()
+	return estimate, nil
+}
+
+// GetRentExemptMinimum returns the lamports required to keep an account of the given size rent exempt
+func (s *Service) GetRentExemptMinimum(ctx context.Context, dataSize uint64) (uint64, error) {
+	s.cacheMu.Lock()
+	lamports, ok := s.rentExempt[dataSize]
+	s.cacheMu.Unlock()
+	if ok {
+		return lamports, nil
+	}
+
+	lamports, err := s.client.GetMinimumBalanceForRentExemption(ctx, dataSize, rpc.CommitmentFinalized)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rent exempt minimum: %v", err)
+	}
 
-	return tokenAccount, exists, nil
+	s.cacheMu.Lock()
+	s.rentExempt[dataSize] = lamports
+	s.cacheMu.Unlock()
+	return lamports, nil
 }
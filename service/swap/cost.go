@@ -0,0 +1,252 @@
+package swap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"swap/internal/datatypes"
+	"swap/internal/utils"
+	"swap/pkg/logger"
+	"swap/service/jupiter"
+	solService "swap/service/solana"
+
+	jupClient "github.com/ilkamo/jupiter-go/jupiter"
+)
+
+// estimatedSwapComputeUnits is a typical compute budget for a routed Jupiter swap
+const estimatedSwapComputeUnits = 300000
+
+// defaultSlippageBps is the slippage tolerance used for swaps (0.05%)
+const defaultSlippageBps = 5
+
+// ErrTradeTooSmall is returned when a swap is skipped for being worth less than MinTradeValue
+var ErrTradeTooSmall = errors.New("trade below the minimum trade value")
+
+// CostEstimate breaks down the estimated cost of a round trip (the pending swap plus
+// the reverse swap that closes the cycle) in units of the current quote asset
+type CostEstimate struct {
+	NetworkFee  float64
+	PriorityFee float64
+	LPFee       float64
+	PlatformFee float64
+	Slippage    float64
+}
+
+// Total returns the sum of all cost components
+func (c CostEstimate) Total() float64 {
+	return c.NetworkFee + c.PriorityFee + c.LPFee + c.PlatformFee + c.Slippage
+}
+
+// shouldSwap applies the cost/benefit check to a pending swap. It returns false when the
+// trade should be deferred to a later cycle. The minimum trade size is enforced for every
+// swap by checkTradeValue.
+func (s *Service) shouldSwap(ctx context.Context, position PositionState, price float64) bool {
+	if !s.config.FeeCheckEnabled {
+		return true
+	}
+
+	params, tradeValue, err := s.pendingSwap(ctx, position, price)
+	if err != nil {
+		logger.Warn("Could not size pending swap for cost check: %v. Proceeding with swap.", err)
+		return true
+	}
+
+	quote := s.currentQuote.Symbol
+	cost, err := s.estimateRoundTripCost(ctx, params, tradeValue, price)
+	if err != nil {
+		logger.Warn("Could not estimate swap cost: %v. Proceeding with swap.", err)
+		return true
+	}
+
+	logger.Info("Estimated round-trip cost for %.2f %s trade: %.4f %s (network %.4f, priority %.4f, LP %.4f, platform %.4f, slippage %.4f)",
+		tradeValue, quote, cost.Total(), quote, cost.NetworkFee, cost.PriorityFee,
+		cost.LPFee, cost.PlatformFee, cost.Slippage)
+
+	// Buy-backs close a round trip that has already been paid for, so only sells are gated
+	if position != InRisk {
+		return true
+	}
+
+	// Selling at the stop protects against a further drop. We assume the drop we are protected
+	// against is the trailing distance, since that is the band the bot re-enters on.
+	protection := tradeValue * s.stopDistance(price) / price
+	required := cost.Total() * s.config.MinBenefitCostRatio
+	if protection < required {
+		logger.Info("Deferring swap: expected protection %.4f %s is below required %.4f %s (cost %.4f x %.2f)",
+			protection, quote, required, quote, cost.Total(), s.config.MinBenefitCostRatio)
+		return false
+	}
+
+	logger.Info("Swap is worthwhile: expected protection %.4f %s covers required %.4f %s", protection, quote, required, quote)
+	return true
+}
+
+// pendingSwap sizes the swap that would be executed from the current position
+// and returns its parameters together with its notional value in quote units
+func (s *Service) pendingSwap(ctx context.Context, position PositionState, price float64) (jupiter.SwapParams, float64, error) {
+	risk := s.tokenPair.Risk
+
+	if position == InRisk {
+		spendable, _, err := s.spendableRisk(ctx)
+		if err != nil {
+			return jupiter.SwapParams{}, 0, err
+		}
+		if spendable.IsZero() {
+			return jupiter.SwapParams{}, 0, fmt.Errorf("not enough %s to swap while maintaining minimum balance", risk.Symbol)
+		}
+		return jupiter.SwapParams{
+			InputMint:   risk.Mint.String(),
+			OutputMint:  s.tokenPair.Quotes[0].Mint.String(),
+			Amount:      spendable.Raw,
+			SlippageBps: defaultSlippageBps,
+			Mode:        jupiter.ExactIn,
+		}, spendable.Float() * price, nil
+	}
+
+	balance, err := s.quoteAvailable(ctx)
+	if err != nil {
+		return jupiter.SwapParams{}, 0, err
+	}
+	return jupiter.SwapParams{
+		InputMint:   s.currentQuote.Mint.String(),
+		OutputMint:  risk.Mint.String(),
+		Amount:      balance.Raw,
+		SlippageBps: defaultSlippageBps,
+		Mode:        jupiter.ExactIn,
+	}, balance.Float(), nil
+}
+
+// estimateRoundTripCost quotes the pending swap and doubles the per-leg cost to
+// account for the reverse swap that will eventually close the cycle
+func (s *Service) estimateRoundTripCost(
+	ctx context.Context,
+	params jupiter.SwapParams,
+	tradeValue float64,
+	price float64,
+) (CostEstimate, error) {
+	quote, err := s.jupiterSvc.GetQuote(ctx, params)
+	if err != nil {
+		return CostEstimate{}, err
+	}
+
+	// Transaction fees are paid in SOL regardless of the traded pair
+	solPrice := s.solPriceInQuote(ctx, price)
+
+	leg := CostEstimate{
+		NetworkFee: float64(solService.BaseFeeLamports) / 1e9 * solPrice,
+	}
+
+	// Priority fee is quoted in micro-lamports per compute unit, at the price the swap will pay
+	priorityLamports := float64(s.computeUnitPrice(ctx)) * estimatedSwapComputeUnits / 1e6
+	leg.PriorityFee = priorityLamports / 1e9 * solPrice
+
+	// Liquidity provider fees charged by each hop of the route
+	for _, step := range quote.RoutePlan {
+		fee, ok := s.tokenAmountToQuote(step.SwapInfo.FeeMint, step.SwapInfo.FeeAmount, price, solPrice)
+		if !ok {
+			logger.Debug("Ignoring LP fee in unsupported mint %s (%s)", step.SwapInfo.FeeMint, step.SwapInfo.Label)
+			continue
+		}
+		leg.LPFee += fee
+	}
+
+	// Platform fee is charged in the output mint
+	if quote.PlatformFee != nil {
+		if fee, ok := s.tokenAmountToQuote(quote.OutputMint, quote.PlatformFee.Amount, price, solPrice); ok {
+			leg.PlatformFee = fee
+		}
+	}
+
+	// Expected slippage is approximated by the quoted price impact
+	leg.Slippage = priceImpactFraction(quote) * tradeValue
+
+	return CostEstimate{
+		NetworkFee:  leg.NetworkFee * 2,
+		PriorityFee: leg.PriorityFee * 2,
+		LPFee:       leg.LPFee * 2,
+		PlatformFee: leg.PlatformFee * 2,
+		Slippage:    leg.Slippage * 2,
+	}, nil
+}
+
+// solPriceInQuote returns the SOL price in quote units, reusing the pair price when SOL is the risk asset
+func (s *Service) solPriceInQuote(ctx context.Context, pairPrice float64) float64 {
+	if s.tokenPair.Risk.Native {
+		return pairPrice
+	}
+
+	quoteMint := s.currentQuote.Mint.String()
+	prices, err := s.solanaService.GetPrices(ctx, SolMint, quoteMint)
+	if err != nil || prices[quoteMint] <= 0 {
+		logger.Warn("Failed to get SOL price for fee estimate, ignoring SOL fees: %v", err)
+		return 0
+	}
+	return prices[SolMint] / prices[quoteMint]
+}
+
+// priceImpactFraction converts a quote's price impact percentage into a fraction
+func priceImpactFraction(quote *jupClient.QuoteResponse) float64 {
+	impactPct, err := utils.ParseFloat(quote.PriceImpactPct)
+	if err != nil || impactPct < 0 {
+		return 0
+	}
+	return impactPct / 100
+}
+
+// tradeValue returns the value of an amount of the risk asset or the current quote asset in quote units
+func (s *Service) tradeValue(ctx context.Context, asset Asset, amount datatypes.TokenAmount) (float64, error) {
+	if !asset.Mint.Equals(s.tokenPair.Risk.Mint) {
+		return amount.Float(), nil
+	}
+	price, err := s.getPairPrice(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return amount.Float() * price, nil
+}
+
+// checkTradeValue skips swaps worth less than MinTradeValue quote units, whose fees would
+// outweigh the trade. value is the swap's value in quote units.
+func (s *Service) checkTradeValue(value float64) error {
+	if !s.config.FeeCheckEnabled || value >= s.config.MinTradeValue {
+		return nil
+	}
+	logger.Info("Skipping swap: trade value %.4f %s is below the minimum trade size %.2f %s",
+		value, s.currentQuote.Symbol, s.config.MinTradeValue, s.currentQuote.Symbol)
+	return fmt.Errorf("%w: %.4f %s", ErrTradeTooSmall, value, s.currentQuote.Symbol)
+}
+
+// computeUnitPrice returns the priority fee in micro-lamports per compute unit that swaps
+// pay, from the median of recent prioritization fees. The cost and reserve estimates use
+// the same price, so they match what is paid. It is 0 when no estimate is available, in
+// which case Jupiter picks the fee.
+func (s *Service) computeUnitPrice(ctx context.Context) uint64 {
+	microLamports, err := s.solanaService.GetPriorityFeeEstimate(ctx)
+	if err != nil {
+		logger.Warn("Failed to estimate priority fee, letting Jupiter pick it: %v", err)
+		return 0
+	}
+	return microLamports
+}
+
+// tokenAmountToQuote converts a raw token amount of SOL, the risk asset or the
+// current quote asset into quote units
+func (s *Service) tokenAmountToQuote(mint string, amount string, price float64, solPrice float64) (float64, bool) {
+	raw, err := strconv.ParseUint(amount, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	switch mint {
+	case s.tokenPair.Risk.Mint.String():
+		return datatypes.TokenAmount{Raw: raw, Decimals: s.tokenPair.Risk.Decimals}.Float() * price, true
+	case s.currentQuote.Mint.String():
+		return datatypes.TokenAmount{Raw: raw, Decimals: s.currentQuote.Decimals}.Float(), true
+	case SolMint:
+		return datatypes.TokenAmount{Raw: raw, Decimals: solDecimals}.Float() * solPrice, true
+	default:
+		return 0, false
+	}
+}
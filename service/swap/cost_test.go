@@ -0,0 +1,37 @@
+package swap
+
+import (
+	"errors"
+	"testing"
+
+	"swap/internal/datatypes"
+)
+
+func TestCheckTradeValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		feeCheck bool
+		value    float64
+		wantErr  bool
+	}{
+		{name: "above the minimum", feeCheck: true, value: 12.5},
+		{name: "at the minimum", feeCheck: true, value: 5},
+		{name: "below the minimum", feeCheck: true, value: 4.99, wantErr: true},
+		{name: "fee check disabled", value: 0.01},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(&datatypes.Config{FeeCheckEnabled: tt.feeCheck, MinTradeValue: 5})
+			s.currentQuote = s.tokenPair.Quotes[0]
+
+			err := s.checkTradeValue(tt.value)
+			if tt.wantErr != errors.Is(err, ErrTradeTooSmall) {
+				t.Fatalf("checkTradeValue(%v) = %v, want too small: %t", tt.value, err, tt.wantErr)
+			}
+			if tt.wantErr && !isSkipped(err) {
+				t.Errorf("a too small trade should count as skipped")
+			}
+		})
+	}
+}
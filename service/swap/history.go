@@ -0,0 +1,54 @@
+package swap
+
+import (
+	"time"
+
+	"swap/pkg/indicators"
+	"swap/pkg/logger"
+	"swap/service/history"
+)
+
+// recordPrice stores a polled price in the history store
+func (s *Service) recordPrice(price float64) {
+	if s.historyStore == nil {
+		return
+	}
+	if err := s.historyStore.Record(history.SeriesName(s.pairName()), time.Now(), price); err != nil {
+		logger.Warn("Failed to record price history: %v", err)
+	}
+}
+
+// loadCandleHistory seeds the indicator and volatility candles from the history store so
+// indicator filters and the adaptive stop don't have to wait for a full series to build up
+// after a restart
+func (s *Service) loadCandleHistory() {
+	if s.historyStore == nil {
+		return
+	}
+	s.loadCandles(s.candles)
+	if s.config.VolatilityStop {
+		s.loadCandles(s.volCandles)
+	}
+}
+
+// loadCandles seeds an aggregator with the recorded candles of its period, if the store keeps them
+func (s *Service) loadCandles(aggregator *indicators.Aggregator) {
+	resolution, ok := history.ResolutionFor(aggregator.Period())
+	if !ok {
+		return
+	}
+
+	// Only candles recent enough to fill the aggregator are read; older ones would be
+	// dropped anyway, and candles from before a long gap don't describe the current market
+	var since time.Time
+	if limit := aggregator.Limit(); limit > 0 {
+		since = time.Now().Add(-time.Duration(limit+1) * aggregator.Period())
+	}
+	candles, err := s.historyStore.Candles(history.SeriesName(s.pairName()), resolution, since, aggregator.Limit())
+	if err != nil {
+		logger.Warn("Failed to load %s candle history: %v", resolution, err)
+		return
+	}
+	aggregator.Load(candles)
+	logger.Info("Loaded %d %s candles from price history", len(aggregator.Candles()), resolution)
+}
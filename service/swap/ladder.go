@@ -0,0 +1,320 @@
+package swap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"swap/internal/datatypes"
+	"swap/internal/utils"
+	"swap/pkg/logger"
+)
+
+// LadderFill records a tranche of the buy-back ladder that has been executed, with the
+// amounts actually swapped
+type LadderFill struct {
+	Tranche    int       `json:"tranche"`
+	Price      float64   `json:"price"`
+	QuoteSpent float64   `json:"quoteSpent"`
+	RiskBought float64   `json:"riskBought"`
+	Time       time.Time `json:"time"`
+}
+
+// LadderState is the persisted progress of a buy-back ladder
+type LadderState struct {
+	Active         bool         `json:"active"`
+	ReferencePrice float64      `json:"referencePrice"` // Stop loss when the ladder started
+	QuoteTotal     float64      `json:"quoteTotal"`     // Quote position being bought back
+	RiskSold       float64      `json:"riskSold"`       // Bought risk already sold by an aborted stop-out
+	StartedAt      time.Time    `json:"startedAt"`
+	Fills          []LadderFill `json:"fills"`
+}
+
+// filled reports whether a tranche has already been executed
+func (l *LadderState) filled(tranche int) bool {
+	for _, fill := range l.Fills {
+		if fill.Tranche == tranche {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteSpent returns the total quote amount spent by all fills
+func (l *LadderState) quoteSpent() float64 {
+	spent := 0.0
+	for _, fill := range l.Fills {
+		spent += fill.QuoteSpent
+	}
+	return spent
+}
+
+// riskBought returns the total risk amount bought by all fills. Fills persisted before
+// the bought amount was recorded are estimated from their price.
+func (l *LadderState) riskBought() float64 {
+	bought := 0.0
+	for _, fill := range l.Fills {
+		if fill.RiskBought > 0 {
+			bought += fill.RiskBought
+		} else if fill.Price > 0 {
+			bought += fill.QuoteSpent / fill.Price
+		}
+	}
+	return bought
+}
+
+// ladderSteps returns the number of tranches in the configured ladder
+func (s *Service) ladderSteps() int {
+	if s.config.LadderTWAPSlices > 0 {
+		return s.config.LadderTWAPSlices
+	}
+	return len(s.config.LadderTranches)
+}
+
+// trancheDue reports whether a tranche may be executed at the given price and time,
+// and the share of the quote position it spends
+func (s *Service) trancheDue(tranche int, price float64, now time.Time) (bool, float64) {
+	if s.config.LadderTWAPSlices > 0 {
+		slices := s.config.LadderTWAPSlices
+		period := time.Duration(s.config.LadderTWAPMinutes) * time.Minute
+		dueAt := s.ladder.StartedAt.Add(period * time.Duration(tranche) / time.Duration(slices))
+		return !now.Before(dueAt), 1 / float64(slices)
+	}
+
+	step := s.config.LadderTranches[tranche]
+	trigger := s.ladder.ReferencePrice * (1 + step.PriceOffsetPct/100)
+	return price >= trigger, step.Fraction
+}
+
+// ladderBuyBack executes at most one due tranche of the buy-back ladder per cycle.
+// It only runs while the price is above the stop loss, so a ladder pauses when the
+// price falls back below it and resumes once it recovers.
+func (s *Service) ladderBuyBack(ctx context.Context, price float64, effectiveStopLoss float64, currentPosition *PositionState) error {
+	quote := s.currentQuote.Symbol
+	steps := s.ladderSteps()
+	if steps == 0 {
+		return fmt.Errorf("buy-back ladder is enabled but has no tranches")
+	}
+
+	if s.ladder == nil || !s.ladder.Active {
+		available, err := s.quoteAvailable(ctx)
+		if err != nil {
+			return err
+		}
+		s.ladder = &LadderState{
+			Active:         true,
+			ReferencePrice: effectiveStopLoss,
+			QuoteTotal:     available.Float(),
+			StartedAt:      time.Now(),
+		}
+		logger.Info("Starting buy-back ladder of %d tranches for %s %s from %.6g %s",
+			steps, available, quote, effectiveStopLoss, quote)
+		s.saveLadderState()
+	}
+
+	for tranche := 0; tranche < steps; tranche++ {
+		if s.ladder.filled(tranche) {
+			continue
+		}
+		due, share := s.trancheDue(tranche, price, time.Now())
+		if !due {
+			continue
+		}
+
+		available, err := s.quoteAvailable(ctx)
+		if err != nil {
+			return err
+		}
+		if available.IsZero() {
+			break
+		}
+
+		// The last tranche spends whatever is left so no dust remains
+		amount := s.ladder.QuoteTotal * share
+		fraction := math.Min(amount/available.Float(), 1)
+		if len(s.ladder.Fills) == steps-1 {
+			fraction = 1
+			amount = available.Float()
+		}
+
+		logger.Info("Buy-back tranche %d/%d due at %.6g %s: spending %.6g %s",
+			tranche+1, steps, price, quote, amount, quote)
+		fill, err := s.buyRiskFill(fraction)
+
+		// A partially filled sliced swap still bought some of the risk asset, which the stop has to protect
+		if !fill.In.IsZero() {
+			s.ladder.Fills = append(s.ladder.Fills, LadderFill{
+				Tranche:    tranche,
+				Price:      price,
+				QuoteSpent: fill.In.Float(),
+				RiskBought: fill.Out.Float(),
+				Time:       time.Now(),
+			})
+			s.saveLadderState()
+		}
+		if isAborted(err) {
+			// The partial fill is recorded above; the next due tranche starts a new order
+			logger.Warn("Buy-back tranche %d/%d aborted after spending %s %s: %v",
+				tranche+1, steps, fill.In, quote, err)
+			return err
+		}
+		if errors.Is(err, ErrTradeTooSmall) {
+			// The tranche would be too small on every later cycle too, so move past it
+			logger.Info("Buy-back tranche %d/%d skipped as too small", tranche+1, steps)
+			s.ladder.Fills = append(s.ladder.Fills, LadderFill{Tranche: tranche, Price: price, Time: time.Now()})
+			break
+		}
+		if err != nil {
+			return s.handleSwapFailure(err, currentPosition)
+		}
+		break
+	}
+
+	if len(s.ladder.Fills) >= steps && s.ladder.quoteSpent() == 0 {
+		logger.Info("Buy-back ladder bought nothing, every tranche was too small")
+		s.resetLadder()
+		return nil
+	}
+	if len(s.ladder.Fills) >= steps {
+		logger.Info("Buy-back ladder complete: spent %.6g %s in %d tranches",
+			s.ladder.quoteSpent(), quote, len(s.ladder.Fills))
+		s.ladder.Active = false
+		*currentPosition = InRisk
+		s.markEntry(price)
+	}
+	s.saveLadderState()
+	return nil
+}
+
+// ladderStopOut sells the risk asset bought by a partially filled ladder once the price falls
+// below the stop loss again, and discards the ladder so a new one starts on the next recovery
+func (s *Service) ladderStopOut(ctx context.Context, price float64) error {
+	risk := s.tokenPair.Risk
+	quote := s.currentQuote
+
+	spendable, _, err := s.spendableRisk(ctx)
+	if err != nil {
+		return err
+	}
+	amount := datatypes.NewTokenAmount(math.Max(s.ladder.riskBought()-s.ladder.RiskSold, 0), risk.Decimals)
+	if amount.Raw > spendable.Raw {
+		amount = spendable
+	}
+	if amount.IsZero() {
+		s.resetLadder()
+		return nil
+	}
+
+	logger.Info("Stop loss triggered at %.6g %s with %d/%d ladder tranches filled! Selling %s %s back to %s...",
+		price, quote.Symbol, len(s.ladder.Fills), s.ladderSteps(), amount, risk.Symbol, quote.Symbol)
+	err = s.attemptSwap(sideSell, func() error {
+		quoteBefore, err := s.assetBalance(ctx, quote)
+		if err != nil {
+			return fmt.Errorf("failed to get %s balance: %v", quote.Symbol, err)
+		}
+		sold, err := s.executeSwap(ctx, risk, quote, amount)
+		if !sold.IsZero() {
+			if s.trackHoldings {
+				s.recordQuoteProceeds(ctx, quote, quoteBefore)
+			}
+			if err != nil {
+				// Only the unsold rest is left to protect
+				s.ladder.RiskSold += sold.Float()
+				s.saveLadderState()
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to perform %s to %s swap: %w", risk.Symbol, quote.Symbol, err)
+		}
+		return nil
+	})
+	if errors.Is(err, ErrTradeTooSmall) {
+		// Selling the remainder isn't worth the fees, so a new ladder starts on the next recovery
+		logger.Info("Leaving %s %s bought by the ladder, too small to sell", amount, risk.Symbol)
+		s.resetLadder()
+		return nil
+	}
+	if err != nil {
+		if isSkipped(err) {
+			return nil
+		}
+		return err
+	}
+
+	s.resetLadder()
+	logger.Info("Sold the ladder's %s back to %s", risk.Symbol, quote.Symbol)
+	return nil
+}
+
+// ladderStatus describes a partially filled ladder for the status output
+func (s *Service) ladderStatus() string {
+	if s.ladder == nil || !s.ladder.Active {
+		return ""
+	}
+
+	bought := 0.0
+	if s.ladder.QuoteTotal > 0 {
+		bought = s.ladder.quoteSpent() / s.ladder.QuoteTotal * 100
+	}
+	return fmt.Sprintf(" (ladder %d/%d tranches filled, %.0f%% bought back)",
+		len(s.ladder.Fills), s.ladderSteps(), bought)
+}
+
+// resetLadder discards any ladder progress, e.g. after selling out of the risk asset again
+func (s *Service) resetLadder() {
+	if s.ladder == nil {
+		return
+	}
+	s.ladder = nil
+	s.saveLadderState()
+}
+
+// stateFile returns the path of a persisted state file for this pair, or "" when persistence is disabled
+func (s *Service) stateFile(kind string) string {
+	if s.config.StateDir == "" {
+		return ""
+	}
+	name := strings.ReplaceAll(s.pairName(), "/", "-")
+	return filepath.Join(s.config.StateDir, kind+"-"+name+".json")
+}
+
+// loadLadderState restores ladder progress persisted by a previous run
+func (s *Service) loadLadderState() {
+	path := s.stateFile("ladder")
+	if path == "" {
+		return
+	}
+
+	var state LadderState
+	if err := utils.ReadJSONFile(path, &state); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Warn("Failed to load ladder state from %s: %v", path, err)
+		}
+		return
+	}
+	if state.Active {
+		s.ladder = &state
+		logger.Info("Resuming buy-back ladder: %d tranches filled", len(state.Fills))
+	}
+}
+
+// saveLadderState persists ladder progress so it survives restarts
+func (s *Service) saveLadderState() {
+	path := s.stateFile("ladder")
+	if path == "" {
+		return
+	}
+
+	state := s.ladder
+	if state == nil {
+		state = &LadderState{}
+	}
+	if err := utils.WriteJSONFile(path, state); err != nil {
+		logger.Warn("Failed to save ladder state to %s: %v", path, err)
+	}
+}
@@ -0,0 +1,118 @@
+package swap
+
+import (
+	"context"
+	"time"
+
+	"swap/pkg/logger"
+	"swap/pkg/metrics"
+	"swap/service/ledger"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+const (
+	// transactionFetchAttempts bounds how often we try to load a confirmed swap for the ledger
+	transactionFetchAttempts = 5
+
+	// transactionFetchDelay is the wait between attempts while the RPC node catches up
+	transactionFetchDelay = 2 * time.Second
+)
+
+// trackEquity values the pair and reports it to the risk manager and to the ledger's
+// buy-and-hold benchmark. Valuing takes several balance and fee RPCs, so it only runs
+// every EquityInterval seconds and on the first cycle after a swap.
+func (s *Service) trackEquity(price float64) {
+	if s.riskManager == nil && s.ledger == nil {
+		return
+	}
+	interval := time.Duration(s.config.EquityInterval) * time.Second
+	if !s.equityDue && !s.lastEquity.IsZero() && time.Since(s.lastEquity) < interval {
+		return
+	}
+	s.equityDue = false
+	s.lastEquity = time.Now()
+
+	allocation, err := s.currentAllocation(s.ctx, price)
+	if err != nil {
+		logger.Warn("Failed to value %s: %v", s.pairName(), err)
+		return
+	}
+
+	riskQuantity := allocation.RiskValue / price
+	metrics.Balance.Set(riskQuantity, s.pairName(), s.tokenPair.Risk.Symbol)
+	metrics.Balance.Set(allocation.QuoteValue, s.pairName(), s.currentQuote.Symbol)
+
+	now := time.Now()
+	if s.riskManager != nil {
+		s.riskManager.Update(s.pairName(), allocation.Total(), now)
+	}
+	if s.ledger != nil {
+		if err := s.ledger.Mark(s.pairName(), now, price, allocation.Total(), riskQuantity); err != nil {
+			logger.Warn("Failed to record equity mark: %v", err)
+		}
+	}
+}
+
+// recordExecution loads a confirmed swap and records its actual amounts in the ledger
+func (s *Service) recordExecution(ctx context.Context, signature string, inputMint string, outputMint string) {
+	if s.ledger == nil || signature == "" {
+		return
+	}
+
+	risk := s.tokenPair.Risk
+	quoteMint := inputMint
+	if quoteMint == risk.Mint.String() {
+		quoteMint = outputMint
+	}
+	quote, ok := s.quoteByMint(quoteMint)
+	if !ok {
+		logger.Warn("Not recording swap %s: %s is not a quote asset of %s", signature, quoteMint, s.pairName())
+		return
+	}
+
+	sig, err := solana.SignatureFromBase58(signature)
+	if err != nil {
+		logger.Warn("Not recording swap with invalid signature %q: %v", signature, err)
+		return
+	}
+
+	var parsed *ledger.ParsedTransaction
+	for attempt := 1; attempt <= transactionFetchAttempts; attempt++ {
+		parsed, err = ledger.FetchTransaction(ctx, s.client, sig, s.publicKey)
+		if err == nil {
+			break
+		}
+		time.Sleep(transactionFetchDelay)
+	}
+	if err != nil {
+		logger.Warn("Failed to load swap %s for the ledger: %v", signature, err)
+		return
+	}
+
+	trade, err := ledger.NewTrade(parsed, s.pairName(), risk.Mint.String(), risk.Symbol, quote.Mint.String(), quote.Symbol)
+	if err != nil {
+		logger.Warn("Failed to record swap %s: %v", signature, err)
+		return
+	}
+	trade.Source = ledger.SourceBot
+	trade.FeeQuote = float64(trade.FeeLamports) / 1e9 * s.solPriceInQuote(ctx, trade.Price)
+
+	if _, err := s.ledger.Append(trade); err != nil {
+		logger.Warn("Failed to record swap %s: %v", signature, err)
+		return
+	}
+	logger.Info("Ledger: %s %.6g %s for %.6g %s at %.6g (fee %.6g %s)",
+		trade.Side, trade.RiskAmount, risk.Symbol, trade.QuoteAmount, quote.Symbol, trade.Price,
+		trade.FeeQuote, quote.Symbol)
+}
+
+// quoteByMint finds a configured quote asset by mint
+func (s *Service) quoteByMint(mint string) (Asset, bool) {
+	for _, quote := range s.tokenPair.Quotes {
+		if quote.Mint.String() == mint {
+			return quote, true
+		}
+	}
+	return Asset{}, false
+}
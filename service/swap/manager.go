@@ -0,0 +1,210 @@
+package swap
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"swap/internal/datatypes"
+	"swap/pkg/logger"
+	"swap/service/health"
+	"swap/service/history"
+	"swap/service/jupiter"
+	"swap/service/ledger"
+	"swap/service/risk"
+	solService "swap/service/solana"
+	"swap/service/token"
+
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	// initialRestartDelay is how long the manager waits before restarting a crashed pair loop
+	initialRestartDelay = time.Second
+
+	// maxRestartDelay caps the exponential backoff between restarts
+	maxRestartDelay = 5 * time.Minute
+
+	// healthyRunDuration is how long a pair loop must run before its backoff is reset
+	healthyRunDuration = 10 * time.Minute
+)
+
+// Options are the optional shared components wired into every pair. Nil fields are disabled.
+type Options struct {
+	History *history.Store  // Records every polled price and its candles
+	Risk    *risk.Manager   // Circuit breaker consulted before every swap
+	Ledger  *ledger.Ledger  // Records executed trades and the buy-and-hold benchmark
+	Health  *health.Monitor // Tracks completed cycles for the health checks and watchdog
+}
+
+// Manager runs one swap service per configured pair. All pairs share the same RPC,
+// price and Jupiter clients, and their wallet transactions are serialized.
+type Manager struct {
+	services []*Service
+}
+
+// NewManager creates a swap service for every pair in cfg.Pairs, or a single
+// service for the top level pair when no pairs are configured
+func NewManager(
+	cfg *datatypes.Config,
+	client *rpc.Client,
+	solanaService *solService.Service,
+	jupiterSvc *jupiter.Service,
+	tokenSvc *token.Service,
+	registry *token.Registry,
+	opts Options,
+) (*Manager, error) {
+	pairConfigs := []*datatypes.Config{cfg}
+	if len(cfg.Pairs) > 0 {
+		pairConfigs = make([]*datatypes.Config, 0, len(cfg.Pairs))
+		for _, pair := range cfg.Pairs {
+			pairConfigs = append(pairConfigs, cfg.ForPair(pair))
+		}
+	}
+
+	walletLock := &sync.Mutex{}
+	manager := &Manager{}
+	for _, pairCfg := range pairConfigs {
+		service, err := NewService(pairCfg, client, solanaService, jupiterSvc, tokenSvc, registry)
+		if err != nil {
+			return nil, fmt.Errorf("pair %s/%v: %v", pairCfg.RiskAsset, pairCfg.QuoteAssets, err)
+		}
+
+		service.walletLock = walletLock
+		service.historyStore = opts.History
+		service.ledger = opts.Ledger
+		if opts.Risk != nil {
+			service.riskManager = opts.Risk
+			opts.Risk.Register(service.pairName())
+		}
+		service.health = opts.Health
+		opts.Health.Register(service.pairName(), time.Duration(pairCfg.CheckInterval)*time.Second)
+		manager.services = append(manager.services, service)
+	}
+
+	// Each pair sells its whole risk asset balance, so no other pair may hold that asset.
+	// This also keeps the pair names, which key the risk, health, ledger and state of a pair, unique.
+	if err := checkRiskAssets(manager.services); err != nil {
+		return nil, err
+	}
+
+	// Pairs sharing a quote asset each track their own part of its balance
+	sharers := quoteSharers(manager.services)
+	for _, service := range manager.services {
+		service.quoteSharers = sharers
+		for _, quote := range service.tokenPair.Quotes {
+			if sharers[quote.Mint.String()] > 1 {
+				service.trackHoldings = true
+			}
+		}
+	}
+
+	return manager, nil
+}
+
+// checkRiskAssets rejects pairs whose risk asset is traded by another pair, as its risk asset
+// or one of its quote assets. A pair's stop would otherwise sell the other pair's position.
+func checkRiskAssets(services []*Service) error {
+	for _, service := range services {
+		risk := service.tokenPair.Risk
+		for _, other := range services {
+			if other == service {
+				continue
+			}
+			if other.tokenPair.Risk.Mint.Equals(risk.Mint) {
+				return fmt.Errorf("pairs %s and %s share the risk asset %s; each pair sells its whole %s balance, so pairs must have different risk assets",
+					service.pairName(), other.pairName(), risk.Symbol, risk.Symbol)
+			}
+			for _, quote := range other.tokenPair.Quotes {
+				if quote.Mint.Equals(risk.Mint) {
+					return fmt.Errorf("pair %s quotes in %s, the risk asset of %s, which sells its whole %s balance",
+						other.pairName(), risk.Symbol, service.pairName(), risk.Symbol)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// quoteSharers counts the pairs trading against each quote mint
+func quoteSharers(services []*Service) map[string]int {
+	sharers := make(map[string]int)
+	for _, service := range services {
+		seen := make(map[string]bool)
+		for _, quote := range service.tokenPair.Quotes {
+			mint := quote.Mint.String()
+			if !seen[mint] {
+				seen[mint] = true
+				sharers[mint]++
+			}
+		}
+	}
+	return sharers
+}
+
+// Start runs every pair loop concurrently and blocks until the context is cancelled
+func (m *Manager) Start(ctx context.Context) error {
+	logger.Info("Starting %d pair strategies", len(m.services))
+
+	var wg sync.WaitGroup
+	for _, service := range m.services {
+		wg.Add(1)
+		go func(service *Service) {
+			defer wg.Done()
+			m.supervise(ctx, service)
+		}(service)
+	}
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+// supervise runs a pair loop and restarts it with exponential backoff if it
+// panics or returns an error, until the context is cancelled
+func (m *Manager) supervise(ctx context.Context, service *Service) {
+	name := service.pairName()
+	delay := initialRestartDelay
+
+	for {
+		started := time.Now()
+		err := runRecovered(ctx, service)
+		if ctx.Err() != nil {
+			logger.Info("Stopped %s strategy", name)
+			return
+		}
+
+		// A loop that ran for a while before failing starts its backoff over
+		if time.Since(started) >= healthyRunDuration {
+			delay = initialRestartDelay
+		}
+
+		logger.Error("%s strategy stopped: %v. Restarting in %s", name, err, delay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if delay > maxRestartDelay {
+			delay = maxRestartDelay
+		}
+	}
+}
+
+// runRecovered runs a pair loop, converting a panic into an error
+func runRecovered(ctx context.Context, service *Service) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return service.Start(ctx)
+}
+
+// pairName returns a display name for the pair, e.g. "SOL/USDC"
+func (s *Service) pairName() string {
+	return s.tokenPair.Risk.Symbol + "/" + s.tokenPair.Quotes[0].Symbol
+}
@@ -0,0 +1,94 @@
+package swap
+
+import (
+	"strings"
+	"testing"
+
+	"swap/internal/datatypes"
+	"swap/service/token"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+var testMints = map[string]string{
+	"SOL":  token.WrappedSOLMint,
+	"USDC": token.KnownMints["USDC"],
+	"USDT": token.KnownMints["USDT"],
+	"JUP":  "JUPyiwrYJFskUPiHa7hkeR8VUtAeFoSYbKedZNsDvCN",
+}
+
+func testAsset(symbol string) Asset {
+	return Asset{Info: token.Info{Symbol: symbol, Mint: solana.MustPublicKeyFromBase58(testMints[symbol]), Decimals: 6}}
+}
+
+// testPair returns a service trading risk against the quote assets
+func testPair(risk string, quotes ...string) *Service {
+	s := newTestService(&datatypes.Config{})
+	s.tokenPair = TokenPair{Risk: testAsset(risk)}
+	for _, quote := range quotes {
+		s.tokenPair.Quotes = append(s.tokenPair.Quotes, testAsset(quote))
+	}
+	s.currentQuote = s.tokenPair.Quotes[0]
+	return s
+}
+
+func TestCheckRiskAssets(t *testing.T) {
+	tests := []struct {
+		name    string
+		pairs   []*Service
+		wantErr string
+	}{
+		{name: "single pair", pairs: []*Service{testPair("SOL", "USDC")}},
+		{name: "shared quote asset", pairs: []*Service{testPair("SOL", "USDC"), testPair("JUP", "USDC", "USDT")}},
+		{name: "shared risk asset", pairs: []*Service{testPair("SOL", "USDC"), testPair("SOL", "USDT")},
+			wantErr: "share the risk asset SOL"},
+		{name: "risk asset quoted by another pair", pairs: []*Service{testPair("SOL", "USDC"), testPair("JUP", "SOL")},
+			wantErr: "JUP/SOL quotes in SOL"},
+		{name: "risk asset as a fallback quote", pairs: []*Service{testPair("JUP", "USDC", "SOL"), testPair("SOL", "USDT")},
+			wantErr: "quotes in SOL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkRiskAssets(tt.pairs)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQuoteShare(t *testing.T) {
+	balance := datatypes.NewTokenAmount(1000, 6)
+	holding := datatypes.NewTokenAmount(400, 6)
+
+	tests := []struct {
+		name     string
+		tracking bool
+		holding  *datatypes.TokenAmount
+		quote    string
+		want     float64
+	}{
+		{name: "unshared quote counts in full", quote: "USDC", want: 1000},
+		{name: "shared quote counts the holding", tracking: true, holding: &holding, quote: "USDC", want: 400},
+		{name: "holding above the balance", tracking: true, holding: &datatypes.TokenAmount{Raw: 2_000_000_000, Decimals: 6},
+			quote: "USDC", want: 1000},
+		{name: "no holding yet", tracking: true, quote: "USDC", want: 0},
+		{name: "other quote asset holds nothing", tracking: true, holding: &holding, quote: "USDT", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testPair("SOL", "USDC", "USDT")
+			s.trackHoldings = tt.tracking
+			s.quoteHolding = tt.holding
+
+			if got := s.quoteShare(testAsset(tt.quote), balance); got.Float() != tt.want {
+				t.Errorf("quoteShare = %s, want %v", got, tt.want)
+			}
+		})
+	}
+}
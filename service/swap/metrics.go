@@ -0,0 +1,17 @@
+package swap
+
+import "swap/pkg/metrics"
+
+// updateMetrics publishes the state of the current cycle to the metrics endpoint
+func (s *Service) updateMetrics(price float64, effectiveStopLoss float64, position PositionState) {
+	pair := s.pairName()
+	metrics.Price.Set(price, pair)
+	metrics.StopPrice.Set(effectiveStopLoss, pair)
+	metrics.HighestPrice.Set(s.config.HighestPrice, pair)
+
+	inRisk := 0.0
+	if position == InRisk {
+		inRisk = 1
+	}
+	metrics.InRisk.Set(inRisk, pair)
+}
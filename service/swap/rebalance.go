@@ -0,0 +1,99 @@
+package swap
+
+import (
+	"context"
+	"fmt"
+	"math"
+
+	"swap/pkg/logger"
+)
+
+// Allocation is the tradable value held on each side of the pair, in quote units
+type Allocation struct {
+	RiskValue  float64
+	QuoteValue float64
+}
+
+// Total returns the combined value of both sides
+func (a Allocation) Total() float64 {
+	return a.RiskValue + a.QuoteValue
+}
+
+// RiskWeight returns the share of the total value held in the risk asset
+func (a Allocation) RiskWeight() float64 {
+	if a.Total() <= 0 {
+		return 0
+	}
+	return a.RiskValue / a.Total()
+}
+
+// currentAllocation values the spendable risk balance and the available quote balance.
+// The SOL reserve is excluded since it can't be traded.
+func (s *Service) currentAllocation(ctx context.Context, price float64) (Allocation, error) {
+	spendable, _, err := s.spendableRisk(ctx)
+	if err != nil {
+		return Allocation{}, err
+	}
+
+	quoteBalance, err := s.quoteAvailable(ctx)
+	if err != nil {
+		return Allocation{}, err
+	}
+
+	return Allocation{
+		RiskValue:  spendable.Float() * price,
+		QuoteValue: quoteBalance.Float(),
+	}, nil
+}
+
+// targetRiskWeight returns the configured risk weight, or the stop weight
+// while the price is below the effective stop loss
+func (s *Service) targetRiskWeight(price float64, effectiveStopLoss float64) float64 {
+	if price < effectiveStopLoss {
+		return s.config.StopRiskWeight
+	}
+	return s.config.TargetRiskWeight
+}
+
+// rebalance swaps between the risk and quote asset to bring the portfolio back to its
+// target weights once it has drifted outside the configured band
+func (s *Service) rebalance(ctx context.Context, price float64, effectiveStopLoss float64) error {
+	risk := s.tokenPair.Risk.Symbol
+	quote := s.currentQuote.Symbol
+
+	allocation, err := s.currentAllocation(ctx, price)
+	if err != nil {
+		return fmt.Errorf("failed to compute allocation: %v", err)
+	}
+	if allocation.Total() <= 0 {
+		return fmt.Errorf("no %s or %s balance to rebalance", risk, quote)
+	}
+
+	target := s.targetRiskWeight(price, effectiveStopLoss)
+	current := allocation.RiskWeight()
+	drift := current - target
+	logger.Info("Allocation: %.1f%% %s (%.6g %s) / %.1f%% %s (%.6g %s), target %.1f%% %s",
+		current*100, risk, allocation.RiskValue, quote, (1-current)*100, quote, allocation.QuoteValue, quote,
+		target*100, risk)
+
+	if math.Abs(drift) <= s.config.RebalanceDriftBand {
+		return nil
+	}
+
+	tradeValue := math.Abs(drift) * allocation.Total()
+	if tradeValue < s.config.RebalanceMinTrade {
+		logger.Info("Skipping rebalance: trade value %.6g %s is below minimum trade size %.6g %s",
+			tradeValue, quote, s.config.RebalanceMinTrade, quote)
+		return nil
+	}
+
+	if drift > 0 {
+		fraction := math.Min(tradeValue/allocation.RiskValue, 1)
+		logger.Info("Rebalancing: selling %.6g %s worth of %s", tradeValue, quote, risk)
+		return ignoreSkipped(s.SellRisk(fraction))
+	}
+
+	fraction := math.Min(tradeValue/allocation.QuoteValue, 1)
+	logger.Info("Rebalancing: buying %.6g %s worth of %s", tradeValue, quote, risk)
+	return ignoreSkipped(s.BuyRisk(fraction))
+}
@@ -0,0 +1,63 @@
+package swap
+
+import (
+	"context"
+
+	"swap/pkg/logger"
+	"swap/service/jupiter"
+	solService "swap/service/solana"
+)
+
+// priorityFeeSafetyMultiplier pads the priority fee estimate to cover fee spikes
+// between now and the time the reserved transactions are actually sent
+const priorityFeeSafetyMultiplier = 2
+
+// solReserve returns the amount of SOL that must stay in the wallet after a swap.
+// With dynamic reserve enabled it covers rent for the token accounts the swaps need
+// plus fees for the configured number of future transactions, never going below
+// the user floor (MinimumSOL). Any estimation failure falls back to the floor.
+func (s *Service) solReserve(ctx context.Context) float64 {
+	floor := s.config.MinimumSOL
+	if !s.config.DynamicReserve {
+		return floor
+	}
+
+	rentPerAccount, err := s.solanaService.GetRentExemptMinimum(ctx, solService.TokenAccountSize)
+	if err != nil {
+		logger.Warn("Failed to compute dynamic SOL reserve, using floor %.4f SOL: %v", floor, err)
+		return floor
+	}
+
+	// Jupiter wraps SOL into a temporary wSOL account for every swap involving SOL,
+	// so that account's rent must always be available while the swap is in flight
+	rentLamports := rentPerAccount
+
+	// Token accounts of the pair must be funded when they don't exist yet. If we can't tell,
+	// reserve the rent anyway rather than risk stranding the wallet.
+	assets := append([]Asset{s.tokenPair.Risk}, s.tokenPair.Quotes...)
+	for _, asset := range assets {
+		if asset.Native {
+			continue
+		}
+		account, err := s.tokenSvc.FindAccount(ctx, asset.Mint)
+		if err != nil || !account.Exists {
+			rentLamports += rentPerAccount
+		}
+	}
+
+	// Swaps pay the same compute unit price; without one Jupiter picks the fee up to its cap
+	priorityLamports := uint64(jupiter.AutoPriorityFeeCapLamports)
+	if microLamports := s.computeUnitPrice(ctx); microLamports > 0 {
+		priorityLamports = microLamports * estimatedSwapComputeUnits / 1e6 * priorityFeeSafetyMultiplier
+	}
+	feeLamports := uint64(s.config.ReserveTxCount) * (solService.BaseFeeLamports + priorityLamports)
+
+	reserve := float64(rentLamports+feeLamports) / 1e9
+	logger.Debug("Computed SOL reserve: %.6f SOL (rent %.6f SOL, fees for %d txs %.6f SOL, floor %.4f SOL)",
+		reserve, float64(rentLamports)/1e9, s.config.ReserveTxCount, float64(feeLamports)/1e9, floor)
+
+	if reserve < floor {
+		return floor
+	}
+	return reserve
+}
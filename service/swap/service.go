@@ -3,47 +3,63 @@ package swap
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 
 	"swap/internal/datatypes"
+	"swap/pkg/indicators"
 	"swap/pkg/logger"
+	"swap/pkg/metrics"
+	"swap/service/execution"
+	"swap/service/health"
+	"swap/service/history"
 	"swap/service/jupiter"
+	"swap/service/ledger"
+	"swap/service/risk"
 	solService "swap/service/solana"
+	"swap/service/token"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
+	jupClient "github.com/ilkamo/jupiter-go/jupiter"
 )
 
-// TokenPair represents the addresses for SOL and USDC
+// Asset is a token the bot trades, together with the wallet's account holding it
+type Asset struct {
+	token.Info
+	Account solana.PublicKey // Token account, or the wallet itself for native SOL
+	Native  bool             // Native SOL is held as lamports rather than in a token account
+}
+
+// TokenPair represents the risk asset and the quote assets it is cycled against
 type TokenPair struct {
-	SOLAddress  solana.PublicKey
-	USDCAddress solana.PublicKey
+	Risk   Asset
+	Quotes []Asset
 }
 
-// PositionState represents which token we're currently holding
+// PositionState represents which side of the pair we're currently holding
 type PositionState string
 
 const (
-	// InSOL indicates the position is in SOL
-	InSOL PositionState = "SOL"
+	// InRisk indicates the position is in the risk asset (e.g. SOL, JUP, BONK)
+	InRisk PositionState = "RISK"
 
-	// InUSDC indicates the position is in USDC
-	InUSDC PositionState = "USDC"
+	// InQuote indicates the position is in one of the configured quote assets
+	InQuote PositionState = "QUOTE"
 )
 
 // Constants for token mints
 const (
 	// SolMint is the address for wrapped SOL
-	SolMint = "So11111111111111111111111111111111111111112"
+	SolMint = token.WrappedSOLMint
 
-	// USDCMint is the address for USDC on mainnet
-	USDCMint = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+	// solDecimals is the number of decimals of native SOL
+	solDecimals = 9
 )
 
-var tokenPair TokenPair
-
 // Service manages the swap operations
 type Service struct {
 	ctx           context.Context
@@ -51,9 +67,61 @@ type Service struct {
 	client        *rpc.Client
 	solanaService *solService.Service
 	jupiterSvc    *jupiter.Service
+	executor      *execution.Executor
+	tokenSvc      *token.Service
 	privateKey    solana.PrivateKey
 	publicKey     solana.PublicKey
 	tokenPair     TokenPair
+	currentQuote  Asset // Quote asset held (or to sell into) on the quote side
+
+	// walletLock serializes transactions from the wallet. The manager shares one
+	// lock between all pairs trading from the same wallet.
+	walletLock sync.Locker
+
+	// quoteHolding tracks how much of the quote asset belongs to this pair: an equal
+	// share of the balance at startup plus the proceeds of its own sells, so pairs
+	// sharing a quote asset don't spend each other's balance. It is only used when
+	// trackHoldings is set; quoteSharers counts the pairs trading against each quote mint.
+	trackHoldings bool
+	quoteHolding  *datatypes.TokenAmount
+	quoteSharers  map[string]int
+
+	// volCandles aggregates polled prices into the candles the adaptive stop distance is measured over
+	volCandles *indicators.Aggregator
+
+	// candles aggregates polled prices into OHLC candles for the indicator filters
+	candles *indicators.Aggregator
+
+	// historyStore records every polled price and its candles; nil disables recording
+	historyStore *history.Store
+
+	// ledger records executed trades and the buy-and-hold benchmark; nil disables it
+	ledger *ledger.Ledger
+
+	// health tracks completed cycles for the health checks and watchdog; nil disables it
+	health *health.Monitor
+
+	// lastEquity is when the pair was last valued; equityDue forces a valuation after a swap
+	lastEquity time.Time
+	equityDue  bool
+
+	// riskManager is the wallet-wide circuit breaker consulted before every swap; nil disables it
+	riskManager *risk.Manager
+
+	// throttle enforces the cooldown and trade frequency limits
+	throttle *tradeThrottle
+
+	// ladder tracks a partially filled buy-back ladder
+	ladder *LadderState
+
+	// Take-profit state: the price we last entered the risk asset at, which levels have
+	// fired since, and the exit price after a full take-profit (0 when not waiting to re-enter)
+	entryPrice     float64
+	takeProfitHits map[int]bool
+	takeProfitExit float64
+
+	// cycleID numbers the monitoring cycles so their log entries can be correlated
+	cycleID uint64
 }
 
 // NewService creates a new swap service
@@ -62,7 +130,16 @@ func NewService(
 	client *rpc.Client,
 	solanaService *solService.Service,
 	jupiterSvc *jupiter.Service,
+	tokenSvc *token.Service,
+	registry *token.Registry,
 ) (*Service, error) {
+	if err := validateEntryFilters(cfg); err != nil {
+		return nil, err
+	}
+	if err := validateTakeProfitLevels(cfg); err != nil {
+		return nil, err
+	}
+
 	// Parse private key
 	privateKey, err := solana.PrivateKeyFromBase58(cfg.PrivateKey)
 	if err != nil {
@@ -74,27 +151,44 @@ func NewService(
 	logger.Info("Using wallet: %s", publicKey.String())
 
 	// Initialize token pair
-	tokenPair, err := initializeTokenAccounts(client, publicKey, USDCMint)
+	tokenPair, err := initializeTokenAccounts(tokenSvc, registry, publicKey, cfg.RiskAsset, cfg.QuoteAssets)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize token accounts: %v", err)
 	}
 
 	// Create service instance
 	service := &Service{
-		ctx:           context.Background(),
-		config:        cfg,
-		client:        client,
-		solanaService: solanaService,
-		jupiterSvc:    jupiterSvc,
-		privateKey:    privateKey,
-		publicKey:     publicKey,
-		tokenPair:     tokenPair,
+		ctx:            context.Background(),
+		config:         cfg,
+		client:         client,
+		solanaService:  solanaService,
+		jupiterSvc:     jupiterSvc,
+		executor:       execution.NewExecutor(jupiterSvc),
+		tokenSvc:       tokenSvc,
+		privateKey:     privateKey,
+		publicKey:      publicKey,
+		tokenPair:      tokenPair,
+		currentQuote:   tokenPair.Quotes[0],
+		takeProfitHits: make(map[int]bool),
+		throttle:       newTradeThrottle(),
+		volCandles:     newVolatilityAggregator(cfg.VolatilityCandlePeriod, cfg.VolatilityWindow),
+		candles: newCandleAggregator(cfg.CandlePeriod, cfg.SlowEMAPeriod, cfg.FastEMAPeriod,
+			cfg.RSIPeriod+1, cfg.BollingerPeriod),
+		walletLock: &sync.Mutex{},
+	}
+
+	service.loadThrottleState()
+	if cfg.LadderEnabled {
+		service.loadLadderState()
+	}
+	if len(cfg.TakeProfitLevels) > 0 {
+		service.loadTakeProfitState()
 	}
 
 	// If dynamic stop loss is enabled, log the configuration
 	if cfg.DynamicStopLoss {
-		logger.Info("Dynamic stop loss enabled: Will adjust to $%.2f below highest price",
-			cfg.StopLossAdjustment)
+		logger.Info("Dynamic stop loss enabled: Will adjust to %.6g %s below highest price",
+			cfg.StopLossAdjustment, service.currentQuote.Symbol)
 	}
 
 	return service, nil
@@ -102,22 +196,47 @@ func NewService(
 
 // Start begins the swap monitoring and execution loop
 func (s *Service) Start(ctx context.Context) error {
+	// Claim this pair's share of a shared quote asset before valuing its position
+	if err := s.seedQuoteHolding(); err != nil {
+		return err
+	}
+
 	// Determine current position based on token balances
 	currentPosition, err := s.determineCurrentPosition()
 	if err != nil {
 		return fmt.Errorf("failed to determine current position: %v", err)
 	}
-	logger.Info("Starting position: %s", currentPosition)
+	logger.Info("Starting position: %s (%s)", currentPosition, s.positionSymbol(currentPosition))
+
+	s.loadCandleHistory()
+
+	// A ladder can't be resumed once the quote side has been spent
+	if currentPosition == InRisk {
+		s.resetLadder()
+	}
 
 	// Main monitoring loop
+	quote := s.currentQuote.Symbol
 	if s.config.DynamicStopLoss {
-		logger.Info("Starting price monitoring with dynamic stop loss:")
-		logger.Info("  - Initial stop loss: $%.2f", s.config.StopLossPrice)
-		logger.Info("  - Dynamic stop loss will be activated when price exceeds $%.2f",
-			s.config.StopLossPrice+s.config.StopLossAdjustment)
-		logger.Info("  - Stop loss will be adjusted to $%.2f below highest price seen", s.config.StopLossAdjustment)
+		logger.Info("Starting %s/%s price monitoring with dynamic stop loss:", s.tokenPair.Risk.Symbol, quote)
+		logger.Info("  - Initial stop loss: %.6g %s", s.config.StopLossPrice, quote)
+		logger.Info("  - Dynamic stop loss will be activated when price exceeds %.6g %s",
+			s.config.StopLossPrice+s.config.StopLossAdjustment, quote)
+		logger.Info("  - Stop loss will be adjusted to %.6g %s below highest price seen", s.config.StopLossAdjustment, quote)
+		if s.config.VolatilityStop {
+			logger.Info("  - Distance adapts to %s volatility over %d %s candles (x%.2f, clamped to %.2f-%.2f%% of the price)",
+				s.config.VolatilityMethod, s.config.VolatilityWindow, s.volCandles.Period(),
+				s.config.VolatilityMultiplier, s.config.MinStopPct, s.config.MaxStopPct)
+		}
 	} else {
-		logger.Info("Starting price monitoring. Fixed stop loss set at $%.2f", s.config.StopLossPrice)
+		logger.Info("Starting %s/%s price monitoring. Fixed stop loss set at %.6g %s",
+			s.tokenPair.Risk.Symbol, quote, s.config.StopLossPrice, quote)
+	}
+
+	if s.config.RebalanceEnabled {
+		logger.Info("Rebalancing to %.0f%% %s (%.0f%% while stopped), drift band %.1f%%",
+			s.config.TargetRiskWeight*100, s.tokenPair.Risk.Symbol, s.config.StopRiskWeight*100,
+			s.config.RebalanceDriftBand*100)
 	}
 
 	ticker := time.NewTicker(time.Duration(s.config.CheckInterval) * time.Second)
@@ -132,45 +251,111 @@ func (s *Service) Start(ctx context.Context) error {
 			if err != nil {
 				logger.Error("Error in monitoring cycle: %v", err)
 			}
+			s.health.CycleCompleted(s.pairName(), err)
 		}
 	}
 }
 
 // monitorAndSwap checks current prices and executes swaps if needed
 func (s *Service) monitorAndSwap(currentPosition *PositionState) error {
-	// Get current SOL price using the Solana service
-	price, err := s.solanaService.GetSOLPrice(s.ctx)
+	risk := s.tokenPair.Risk.Symbol
+	quote := s.currentQuote.Symbol
+
+	s.cycleID++
+	metrics.Cycles.Inc(s.pairName())
+
+	// Get current price of the risk asset in quote units
+	price, err := s.getPairPrice(s.ctx)
 	if err != nil {
-		logger.Error("Error getting SOL price: %v. Skipping this cycle.", err)
+		metrics.PriceFetchErrors.Inc(s.pairName())
+		logger.With(logger.Fields{"pair": s.pairName(), "cycle_id": s.cycleID}).Error("Error getting %s price: %v. Skipping this cycle.", risk, err)
 		return err
 	}
 
+	s.health.PriceUpdated(s.pairName())
+
+	// Build the candles for the indicator filters and the volatility-adaptive stop distance
+	now := time.Now()
+	s.candles.Add(now, price)
+	s.volCandles.Add(now, price)
+	s.recordPrice(price)
+	s.trackEquity(price)
+
 	// Calculate the effective stop loss price
 	effectiveStopLoss := s.calculateDynamicStopLoss(price)
+	s.updateMetrics(price, effectiveStopLoss, *currentPosition)
+
+	logger.With(logger.Fields{
+		"pair":     s.pairName(),
+		"cycle_id": s.cycleID,
+		"price":    price,
+		"stop":     effectiveStopLoss,
+		"position": s.positionSymbol(*currentPosition),
+	}).Info("Current %s price: %.6g %s, Stop loss: %.6g %s, Position: %s%s",
+		risk, price, quote, effectiveStopLoss, quote, s.positionSymbol(*currentPosition), s.ladderStatus())
+
+	// In rebalancing mode the stop only changes the target weights
+	if s.config.RebalanceEnabled {
+		return s.rebalance(s.ctx, price, effectiveStopLoss)
+	}
 
-	logger.Info("Current SOL price: $%.2f, Stop loss: $%.2f, Position: %s",
-		price, effectiveStopLoss, *currentPosition)
+	// Without a recorded buy-back, the first price seen in the risk asset is the entry
+	if *currentPosition == InRisk && s.entryPrice == 0 {
+		s.markEntry(price)
+	}
 
 	// Check if we need to swap based on price and current position
-	if *currentPosition == InSOL && price < effectiveStopLoss {
-		// If we're in SOL and price drops below stop loss, swap to USDC
-		logger.Info("Stop loss triggered at $%.2f! Swapping SOL to USDC...", price)
-		err = s.swapSOLToUSDC()
+	if *currentPosition == InRisk && price < effectiveStopLoss {
+		// If we're in the risk asset and price drops below stop loss, swap to the quote asset
+		logger.Info("Stop loss triggered at %.6g %s! Swapping %s to quote asset...", price, quote, risk)
+		if !s.shouldSwap(s.ctx, *currentPosition, price) {
+			return nil
+		}
+		sold, err := s.swapRiskToQuote()
+		if isAborted(err) {
+			// The slices already sold stay sold; the rest is still held and protected by the stop
+			logger.Warn("Stop loss sell aborted after selling %s %s, keeping the rest under the stop: %v", sold, risk, err)
+			return err
+		}
 		if err != nil {
 			return s.handleSwapFailure(err, currentPosition)
 		}
-		*currentPosition = InUSDC
-		logger.Info("Successfully swapped to USDC")
-	} else if *currentPosition == InUSDC && price > effectiveStopLoss {
-		// Buy back into SOL if price is above the stop loss
-		logger.Info("Buy back triggered at $%.2f (above stop loss $%.2f)! Swapping USDC to SOL...",
-			price, effectiveStopLoss)
-		err = s.swapUSDCToSOL()
+		*currentPosition = InQuote
+		s.resetLadder()
+		logger.Info("Successfully swapped to %s", s.currentQuote.Symbol)
+	} else if *currentPosition == InQuote && price < effectiveStopLoss && s.ladder != nil && s.ladder.Active && len(s.ladder.Fills) > 0 {
+		// The tranches bought so far are a risk position the stop has to protect
+		return s.ladderStopOut(s.ctx, price)
+	} else if *currentPosition == InRisk && len(s.config.TakeProfitLevels) > 0 {
+		// Sell into strength at the configured take-profit levels
+		_, err := s.checkTakeProfit(price, currentPosition)
+		return err
+	} else if *currentPosition == InQuote && price > effectiveStopLoss && !s.entryAllowed() {
+		// Indicator filters hold back any kind of buy-back until they agree
+		return nil
+	} else if *currentPosition == InQuote && s.takeProfitExit > 0 {
+		// After a full take-profit exit, the re-entry rule decides when to buy back
+		return s.takeProfitReentry(price, effectiveStopLoss, currentPosition)
+	} else if *currentPosition == InQuote && price > effectiveStopLoss && s.config.LadderEnabled {
+		// Buy back in tranches rather than all at once
+		return s.ladderBuyBack(s.ctx, price, effectiveStopLoss, currentPosition)
+	} else if *currentPosition == InQuote && price > effectiveStopLoss {
+		// Buy back into the risk asset if price is above the stop loss
+		logger.Info("Buy back triggered at %.6g %s (above stop loss %.6g %s)! Swapping %s to %s...",
+			price, quote, effectiveStopLoss, quote, quote, risk)
+		if !s.shouldSwap(s.ctx, *currentPosition, price) {
+			return nil
+		}
+		fill, err := s.swapQuoteToRisk()
+		if isAborted(err) {
+			return s.keepPartialBuy(fill, price, currentPosition, err)
+		}
 		if err != nil {
 			return s.handleSwapFailure(err, currentPosition)
 		}
-		*currentPosition = InSOL
-		logger.Info("Successfully swapped to SOL")
+		*currentPosition = InRisk
+		s.markEntry(price)
+		logger.Info("Successfully swapped to %s", risk)
 	}
 
 	return nil
@@ -183,25 +368,27 @@ func (s *Service) calculateDynamicStopLoss(currentPrice float64) float64 {
 		return s.config.StopLossPrice
 	}
 
+	adjustment := s.stopDistance(currentPrice)
+
 	// Only consider updating the highest price if it's significantly higher than the initial stop loss
 	// This ensures we don't lower the stop loss below the initial value
-	if currentPrice > (s.config.StopLossPrice + s.config.StopLossAdjustment) {
+	if currentPrice > (s.config.StopLossPrice + adjustment) {
 		// Update the highest price seen if current price is significantly higher than previous highest
-		if s.config.HighestPrice == 0 || currentPrice > (s.config.HighestPrice+s.config.StopLossAdjustment) {
+		if s.config.HighestPrice == 0 || currentPrice > (s.config.HighestPrice+adjustment) {
 			s.config.HighestPrice = currentPrice
-			logger.Info("New highest price recorded: $%.2f", currentPrice)
+			logger.Info("New highest price recorded: %.6g", currentPrice)
 
 			// Calculate new stop loss based on highest price
-			dynamicStopLoss := s.config.HighestPrice - s.config.StopLossAdjustment
-			logger.Info("Dynamic stop loss adjusted to $%.2f (highest price $%.2f - adjustment $%.2f)",
-				dynamicStopLoss, s.config.HighestPrice, s.config.StopLossAdjustment)
+			dynamicStopLoss := s.config.HighestPrice - adjustment
+			logger.Info("Dynamic stop loss adjusted to %.6g (highest price %.6g - adjustment %.6g)",
+				dynamicStopLoss, s.config.HighestPrice, adjustment)
 
 			return dynamicStopLoss
 		}
 
 		// If we have a recorded highest price that's significantly above the initial stop loss
-		if s.config.HighestPrice > (s.config.StopLossPrice + s.config.StopLossAdjustment) {
-			calculatedStopLoss := s.config.HighestPrice - s.config.StopLossAdjustment
+		if s.config.HighestPrice > (s.config.StopLossPrice + adjustment) {
+			calculatedStopLoss := s.config.HighestPrice - adjustment
 			// Ensure the calculated stop loss is not lower than the initial stop loss
 			if calculatedStopLoss > s.config.StopLossPrice {
 				return calculatedStopLoss
@@ -214,101 +401,242 @@ func (s *Service) calculateDynamicStopLoss(currentPrice float64) float64 {
 }
 
 // Initialize token accounts and return the token pair
-func initializeTokenAccounts(client *rpc.Client, publicKey solana.PublicKey, usdcMint string) (TokenPair, error) {
+func initializeTokenAccounts(
+	tokenSvc *token.Service,
+	registry *token.Registry,
+	publicKey solana.PublicKey,
+	riskAsset string,
+	quoteAssets []string,
+) (TokenPair, error) {
 	tokenPair := TokenPair{}
 
-	// SOL is native, so the address is the same as the wallet
-	tokenPair.SOLAddress = publicKey
+	if len(quoteAssets) == 0 {
+		return tokenPair, fmt.Errorf("no quote assets configured")
+	}
 
-	// For USDC, we need to find the associated token account
-	usdcMintPubkey, err := solana.PublicKeyFromBase58(usdcMint)
+	ctx := context.Background()
+
+	risk, err := resolveAsset(ctx, tokenSvc, registry, publicKey, riskAsset)
 	if err != nil {
-		return tokenPair, fmt.Errorf("invalid USDC mint address: %v", err)
+		return tokenPair, fmt.Errorf("failed to resolve risk asset %s: %v", riskAsset, err)
 	}
+	tokenPair.Risk = risk
 
-	// Find the USDC token account
-	usdcAccount, _, err := solana.FindAssociatedTokenAddress(publicKey, usdcMintPubkey)
-	logger.Info("USDC mint: %s", usdcMintPubkey)
+	for _, symbolOrMint := range quoteAssets {
+		quote, err := resolveAsset(ctx, tokenSvc, registry, publicKey, symbolOrMint)
+		if err != nil {
+			return tokenPair, fmt.Errorf("failed to resolve quote asset %s: %v", symbolOrMint, err)
+		}
+		if quote.Mint.Equals(risk.Mint) {
+			return tokenPair, fmt.Errorf("quote asset %s is the same as the risk asset", quote.Symbol)
+		}
+		tokenPair.Quotes = append(tokenPair.Quotes, quote)
+	}
+
+	return tokenPair, nil
+}
+
+// resolveAsset resolves a token's mint information from chain and finds the wallet's account for it
+func resolveAsset(
+	ctx context.Context,
+	tokenSvc *token.Service,
+	registry *token.Registry,
+	publicKey solana.PublicKey,
+	symbolOrMint string,
+) (Asset, error) {
+	// Resolve the mint's decimals and token program from chain
+	info, err := registry.Resolve(ctx, symbolOrMint)
 	if err != nil {
-		return tokenPair, fmt.Errorf("failed to derive USDC token account: %v", err)
+		return Asset{}, err
+	}
+	logger.Info("%s mint: %s (%d decimals)", info.Symbol, info.Mint, info.Decimals)
+
+	// SOL is native, so the address is the same as the wallet
+	if info.Mint.String() == SolMint {
+		return Asset{Info: info, Account: publicKey, Native: true}, nil
 	}
-	logger.Info("USDC token account: %s", usdcAccount.String())
 
-	// Check if the token account exists
-	_, err = client.GetAccountInfo(context.Background(), usdcAccount)
+	// Find the token account
+	account, err := tokenSvc.FindAccount(ctx, info.Mint)
 	if err != nil {
-		// Account doesn't exist, we would need to create it
-		logger.Warn("USDC token account does not exist. It will be created during the first swap.")
+		return Asset{}, fmt.Errorf("failed to find %s token account: %v", info.Symbol, err)
+	}
+	if !account.Exists {
+		// EnsureAccounts creates every pair's accounts at startup, so a missing one is a setup error
+		return Asset{}, fmt.Errorf("%s token account %s does not exist", info.Symbol, account.Address)
 	}
+	logger.Info("%s token account: %s", info.Symbol, account.Address)
 
-	tokenPair.USDCAddress = usdcAccount
-	logger.Info("USDC token account: %s", usdcAccount.String())
+	return Asset{Info: info, Account: account.Address}, nil
+}
 
-	return tokenPair, nil
+// getPairPrice returns the current price of the risk asset in units of the current quote asset
+func (s *Service) getPairPrice(ctx context.Context) (float64, error) {
+	riskMint := s.tokenPair.Risk.Mint.String()
+	quoteMint := s.currentQuote.Mint.String()
+
+	prices, err := s.solanaService.GetPrices(ctx, riskMint, quoteMint)
+	if err != nil {
+		return 0, err
+	}
+	if prices[quoteMint] <= 0 {
+		return 0, fmt.Errorf("invalid %s price: %v", s.currentQuote.Symbol, prices[quoteMint])
+	}
+
+	return prices[riskMint] / prices[quoteMint], nil
 }
 
-// Determine if we are currently in SOL or USDC
+// assetBalance returns the wallet's balance of an asset in base units
+func (s *Service) assetBalance(ctx context.Context, asset Asset) (datatypes.TokenAmount, error) {
+	if asset.Native {
+		return s.solanaService.CheckSolBalance(ctx, s.publicKey)
+	}
+	return s.solanaService.GetTokenBalance(ctx, asset.Account, asset.Decimals)
+}
+
+// positionSymbol returns the symbol of the asset held in the given position
+func (s *Service) positionSymbol(position PositionState) string {
+	if position == InQuote {
+		return s.currentQuote.Symbol
+	}
+	return s.tokenPair.Risk.Symbol
+}
+
+// Determine if we are currently in the risk asset or a quote asset. Only the spendable risk
+// balance and, for a shared quote asset, this pair's holding count towards the position.
 func (s *Service) determineCurrentPosition() (PositionState, error) {
 	ctx := context.Background()
+	risk := s.tokenPair.Risk
 
-	// Check SOL balance using the Solana service
-	solBalance, err := s.solanaService.CheckSolBalance(ctx, s.publicKey)
+	// Check risk asset balance
+	riskBalance, reserve, err := s.spendableRisk(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to get SOL balance: %v", err)
+		return "", err
 	}
+	logger.Info("Current %s balance: %s (plus %s reserved)", risk.Symbol, riskBalance, reserve)
 
-	// Check USDC balance
-	tokenBalance, err := s.client.GetTokenAccountBalance(
-		ctx,
-		s.tokenPair.USDCAddress,
-		rpc.CommitmentFinalized,
-	)
-	// If USDC account doesn't exist yet, we're in SOL
+	// Both sides are compared by their USD value
+	mints := []string{risk.Mint.String()}
+	for _, quote := range s.tokenPair.Quotes {
+		mints = append(mints, quote.Mint.String())
+	}
+	prices, err := s.solanaService.GetPrices(ctx, mints...)
 	if err != nil {
-		return InSOL, nil
+		return "", fmt.Errorf("failed to get asset prices: %v", err)
 	}
+	riskValue := riskBalance.Float() * prices[risk.Mint.String()]
 
-	// Convert balances to comparable values
-	var usdcBalanceFloat float64
-	if tokenBalance != nil && tokenBalance.Value != nil {
-		usdcBalanceFloat, _ = strconv.ParseFloat(tokenBalance.Value.Amount, 64)
-		usdcBalanceFloat = usdcBalanceFloat / 1e6 // USDC has 6 decimals
+	// Find the quote asset with the largest balance held by this pair
+	largestValue := 0.0
+	largest := s.currentQuote
+	for _, quote := range s.tokenPair.Quotes {
+		balance, err := s.assetBalance(ctx, quote)
+		if err != nil {
+			return "", fmt.Errorf("failed to get %s balance: %v", quote.Symbol, err)
+		}
+		held := s.quoteShare(quote, balance)
+		logger.Info("Current %s balance: %s (%s held by %s)", quote.Symbol, balance, held, s.pairName())
+
+		value := held.Float() * prices[quote.Mint.String()]
+		if value > largestValue {
+			largestValue = value
+			largest = quote
+		}
+	}
+	if !largest.Mint.Equals(s.currentQuote.Mint) {
+		s.quoteHolding = nil
 	}
+	s.currentQuote = largest
 
-	logger.Info("Current balances: %.4f SOL, %.2f USDC", solBalance, usdcBalanceFloat)
+	// The pair is in the quote asset when it holds more than dust there and more than in the risk asset
+	if largestValue > 1.0 && largestValue > riskValue {
+		return InQuote, nil
+	}
+
+	return InRisk, nil
+}
 
-	// Determine position based on which token has more value
-	if usdcBalanceFloat > 1.0 {
-		return InUSDC, nil
+// attemptSwap is a utility function to handle swap attempts with retry logic.
+// All swaps go through here, so it also enforces the trade frequency limits.
+func (s *Service) attemptSwap(side tradeSide, swapFunc func() error) error {
+	if s.riskManager != nil {
+		if err := s.riskManager.Check(); err != nil {
+			logger.Warn("Skipping %s: %v", side, err)
+			return err
+		}
+	}
+	if reason := s.checkThrottle(side, time.Now()); reason != "" {
+		return throttled(reason)
+	}
+	// An aborted order may have filled some slices, so it counts as a trade
+	err := s.retrySwap(swapFunc)
+	if err != nil && !isAborted(err) {
+		return err
 	}
+	s.recordTrade(side, time.Now())
+	s.equityDue = true
+	return err
+}
+
+// isAborted reports whether a sliced order was cancelled part way by its price guards.
+// Slices already executed stay filled, so an aborted order is neither retried nor undone.
+func isAborted(err error) bool {
+	return errors.Is(err, execution.ErrAborted)
+}
 
-	return InSOL, nil
+// keepPartialBuy handles a buy-back aborted part way. Any risk asset bought is a position
+// the stop has to protect, so the pair switches to it and the unspent quote asset stays idle
+// until the next exit.
+func (s *Service) keepPartialBuy(fill swapFill, price float64, currentPosition *PositionState, err error) error {
+	if fill.In.IsZero() {
+		logger.Warn("Buy-back aborted before any slice filled: %v", err)
+		return err
+	}
+	logger.Warn("Buy-back aborted after buying %s %s for %s %s, protecting it with the stop: %v",
+		fill.Out, s.tokenPair.Risk.Symbol, fill.In, s.currentQuote.Symbol, err)
+	*currentPosition = InRisk
+	s.markEntry(price)
+	return err
 }
 
-// attemptSwap is a utility function to handle swap attempts with retry logic
-func (s *Service) attemptSwap(swapFunc func() error) error {
+// retrySwap runs a swap, retrying failed attempts when retries are enabled
+func (s *Service) retrySwap(swapFunc func() error) error {
+	// Waiting for the wallet, confirmations and retries may hold up the loop for several intervals
+	s.health.SwapStarted(s.pairName())
+	defer s.health.SwapFinished(s.pairName())
+
 	// If retries are disabled, only try once
 	if !s.config.EnableRetry {
 		logger.Info("Retry is disabled. Attempting swap once.")
-		err := swapFunc()
-		if err != nil {
+		err := s.runLocked(swapFunc)
+		if err != nil && !isSkipped(err) {
 			logger.Error("Swap failed: %v", err)
-			return err
 		}
-		return nil
+		return err
 	}
 
 	// If retries are enabled, try multiple times
 	for attempt := 1; attempt <= s.config.RetryAttempts; attempt++ {
 		logger.Info("Swap attempt %d/%d", attempt, s.config.RetryAttempts)
 
-		err := swapFunc()
+		err := s.runLocked(swapFunc)
 		if err == nil {
 			return nil // Success
 		}
 
+		// Retrying would start a new order at a fresh reference price and defeat the abort
+		if isAborted(err) {
+			logger.Warn("Swap aborted, not retrying: %v", err)
+			return err
+		}
+		// A swap skipped for its size would be skipped again
+		if isSkipped(err) {
+			return err
+		}
+
 		logger.Error("Swap failed: %v", err)
 		if attempt < s.config.RetryAttempts {
+			metrics.SwapRetries.Inc(s.pairName())
 			logger.Info("Retrying in %d seconds...", s.config.RetryDelay)
 			time.Sleep(time.Duration(s.config.RetryDelay) * time.Second)
 		}
@@ -317,107 +645,539 @@ func (s *Service) attemptSwap(swapFunc func() error) error {
 	return fmt.Errorf("failed to swap after %d attempts", s.config.RetryAttempts)
 }
 
-// Swap SOL to USDC
-func (s *Service) swapSOLToUSDC() error {
-	return s.attemptSwap(s.executeSOLToUSDCSwap)
+// runLocked executes a swap while holding the wallet lock so transactions from the same
+// wallet don't race on blockhashes or balances, then reclaims any leftover wrapped SOL
+func (s *Service) runLocked(swapFunc func() error) error {
+	s.walletLock.Lock()
+	defer s.walletLock.Unlock()
+
+	if err := swapFunc(); err != nil {
+		return err
+	}
+	s.reclaimWrappedSOL()
+	return nil
 }
 
-// Swap USDC to SOL
-func (s *Service) swapUSDCToSOL() error {
-	return s.attemptSwap(s.executeUSDCToSOLSwap)
+// reclaimWrappedSOL closes any wrapped SOL account Jupiter left open after a swap
+func (s *Service) reclaimWrappedSOL() {
+	if s.tokenSvc == nil {
+		return
+	}
+	if err := s.tokenSvc.CloseWrappedSOL(s.ctx); err != nil {
+		logger.Warn("Failed to reclaim wrapped SOL: %v", err)
+	}
 }
 
-// Execute the actual SOL to USDC swap
-func (s *Service) executeSOLToUSDCSwap() error {
-	logger.Info("executing sol to usdc swap")
+// Swap the risk asset to a quote asset, returning the risk amount sold
+func (s *Service) swapRiskToQuote() (datatypes.TokenAmount, error) {
+	return s.sellRiskFill(1.0)
+}
+
+// Swap the held quote asset to the risk asset, returning the amounts swapped
+func (s *Service) swapQuoteToRisk() (swapFill, error) {
+	return s.buyRiskFill(1.0)
+}
+
+// SellRisk swaps a fraction (0, 1] of the spendable risk asset balance to a quote asset.
+// For native SOL the spendable balance is the SOL balance minus the computed SOL reserve.
+func (s *Service) SellRisk(fraction float64) error {
+	_, err := s.sellRiskFill(fraction)
+	return err
+}
+
+// sellRiskFill sells a fraction (0, 1] of the spendable risk asset balance like SellRisk,
+// returning the risk amount actually sold over all attempts
+func (s *Service) sellRiskFill(fraction float64) (datatypes.TokenAmount, error) {
+	sold := datatypes.TokenAmount{Decimals: s.tokenPair.Risk.Decimals}
+	if err := validateFraction(fraction); err != nil {
+		return sold, err
+	}
+	err := s.attemptSwap(sideSell, func() error {
+		filled, err := s.riskToQuoteFill(fraction)
+		sold.Raw += filled.Raw
+		return err
+	})
+	return sold, err
+}
+
+// BuyRisk swaps a fraction (0, 1] of the held quote asset balance to the risk asset
+func (s *Service) BuyRisk(fraction float64) error {
+	if err := validateFraction(fraction); err != nil {
+		return err
+	}
+	return s.attemptSwap(sideBuy, func() error { return s.executeQuoteToRiskSwap(fraction) })
+}
+
+// buyRiskFill swaps a fraction (0, 1] of the held quote asset balance to the risk asset like
+// BuyRisk, returning the amounts actually swapped over all attempts
+func (s *Service) buyRiskFill(fraction float64) (swapFill, error) {
+	if err := validateFraction(fraction); err != nil {
+		return swapFill{}, err
+	}
+	total := swapFill{
+		In:  datatypes.TokenAmount{Decimals: s.currentQuote.Decimals},
+		Out: datatypes.TokenAmount{Decimals: s.tokenPair.Risk.Decimals},
+	}
+	err := s.attemptSwap(sideBuy, func() error {
+		fill, err := s.quoteToRiskFill(fraction)
+		total.In.Raw += fill.In.Raw
+		total.Out.Raw += fill.Out.Raw
+		return err
+	})
+	return total, err
+}
+
+// BuyExactRisk spends as much of this pair's quote asset as needed to receive exactly the given amount of the risk asset
+func (s *Service) BuyExactRisk(riskAmount float64) error {
+	if riskAmount <= 0 {
+		return fmt.Errorf("invalid %s amount: %v", s.tokenPair.Risk.Symbol, riskAmount)
+	}
+	return s.attemptSwap(sideBuy, func() error {
+		ctx := context.Background()
+		available, err := s.quoteAvailable(ctx)
+		if err != nil {
+			return err
+		}
+
+		amount := datatypes.NewTokenAmount(riskAmount, s.tokenPair.Risk.Decimals)
+		spent, err := s.executeExactOutSwap(ctx, s.currentQuote, s.tokenPair.Risk, amount, available)
+		s.spendQuote(spent)
+		return err
+	})
+}
+
+// SellRiskForExactQuote sells as much of the spendable risk asset as needed to receive exactly the given amount of the current quote asset
+func (s *Service) SellRiskForExactQuote(quoteAmount float64) error {
+	if quoteAmount <= 0 {
+		return fmt.Errorf("invalid %s amount: %v", s.currentQuote.Symbol, quoteAmount)
+	}
+	return s.attemptSwap(sideSell, func() error {
+		ctx := context.Background()
+		quote := s.currentQuote
+		spendable, _, err := s.spendableRisk(ctx)
+		if err != nil {
+			return err
+		}
+
+		// Remember the quote balance so we can attribute the proceeds to this pair
+		quoteBefore, err := s.assetBalance(ctx, quote)
+		if err != nil {
+			return fmt.Errorf("failed to get %s balance: %v", quote.Symbol, err)
+		}
+
+		amount := datatypes.NewTokenAmount(quoteAmount, quote.Decimals)
+		if _, err := s.executeExactOutSwap(ctx, s.tokenPair.Risk, quote, amount, spendable); err != nil {
+			return err
+		}
+		if s.trackHoldings {
+			s.recordQuoteProceeds(ctx, quote, quoteBefore)
+		}
+		return nil
+	})
+}
+
+// validateFraction ensures a position fraction is within (0, 1]
+func validateFraction(fraction float64) error {
+	if fraction <= 0 || fraction > 1 {
+		return fmt.Errorf("fraction must be in (0, 1], got %.4f", fraction)
+	}
+	return nil
+}
+
+// spendableRisk returns the risk asset balance that may be swapped and the amount held back.
+// Only native SOL keeps a reserve, since it also pays for transaction fees and rent.
+func (s *Service) spendableRisk(ctx context.Context) (datatypes.TokenAmount, datatypes.TokenAmount, error) {
+	risk := s.tokenPair.Risk
+	balance, err := s.assetBalance(ctx, risk)
+	if err != nil {
+		return datatypes.TokenAmount{}, datatypes.TokenAmount{}, fmt.Errorf("failed to get %s balance: %v", risk.Symbol, err)
+	}
+
+	if !risk.Native {
+		return balance, datatypes.TokenAmount{Decimals: risk.Decimals}, nil
+	}
+
+	reserve := datatypes.NewTokenAmount(s.solReserve(ctx), solDecimals)
+	return balance.Sub(reserve), reserve, nil
+}
+
+// selectQuote picks the quote asset to sell the risk asset into. With BestQuoteAsset enabled
+// it quotes every configured quote asset and picks the one returning the most dollars.
+func (s *Service) selectQuote(ctx context.Context, amount datatypes.TokenAmount) Asset {
+	if !s.config.BestQuoteAsset || len(s.tokenPair.Quotes) < 2 {
+		return s.tokenPair.Quotes[0]
+	}
+
+	mints := make([]string, 0, len(s.tokenPair.Quotes))
+	for _, quote := range s.tokenPair.Quotes {
+		mints = append(mints, quote.Mint.String())
+	}
+	prices, err := s.solanaService.GetPrices(ctx, mints...)
+	if err != nil {
+		logger.Warn("Failed to get quote asset prices, using %s: %v", s.tokenPair.Quotes[0].Symbol, err)
+		return s.tokenPair.Quotes[0]
+	}
+
+	best := s.tokenPair.Quotes[0]
+	bestValue := 0.0
+	for _, quote := range s.tokenPair.Quotes {
+		response, err := s.jupiterSvc.GetQuote(ctx, jupiter.SwapParams{
+			InputMint:   s.tokenPair.Risk.Mint.String(),
+			OutputMint:  quote.Mint.String(),
+			Amount:      amount.Raw,
+			SlippageBps: defaultSlippageBps,
+			Mode:        jupiter.ExactIn,
+		})
+		if err != nil {
+			logger.Warn("Failed to quote %s to %s: %v", s.tokenPair.Risk.Symbol, quote.Symbol, err)
+			continue
+		}
+
+		outRaw, err := strconv.ParseUint(response.OutAmount, 10, 64)
+		if err != nil {
+			continue
+		}
+		out := datatypes.TokenAmount{Raw: outRaw, Decimals: quote.Decimals}
+		value := out.Float() * prices[quote.Mint.String()]
+		logger.Info("Quote for %s %s to %s: %s (~$%.2f)", amount, s.tokenPair.Risk.Symbol, quote.Symbol, out, value)
+
+		if value > bestValue {
+			best = quote
+			bestValue = value
+		}
+	}
+
+	logger.Info("Selected %s as best quote asset", best.Symbol)
+	return best
+}
+
+// Execute the actual risk to quote swap for a fraction of the spendable balance
+func (s *Service) executeRiskToQuoteSwap(fraction float64) error {
+	_, err := s.riskToQuoteFill(fraction)
+	return err
+}
+
+// riskToQuoteFill swaps a fraction of the spendable risk asset balance to a quote asset and
+// returns the risk amount sold, also after a partial fill
+func (s *Service) riskToQuoteFill(fraction float64) (datatypes.TokenAmount, error) {
+	risk := s.tokenPair.Risk
+	logger.Info("executing %s to quote asset swap", risk.Symbol)
 
 	ctx := context.Background()
+	sold := datatypes.TokenAmount{Decimals: risk.Decimals}
 
-	// Get current SOL balance using the Solana service
-	solBalance, err := s.solanaService.CheckSolBalance(ctx, s.publicKey)
+	// Calculate swap amount, keeping enough SOL to pay for the buy-back
+	spendable, reserve, err := s.spendableRisk(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get SOL balance: %v", err)
+		return sold, err
+	}
+	swapAmount := spendable.Fraction(fraction)
+	if swapAmount.IsZero() {
+		return sold, fmt.Errorf("not enough %s to swap while maintaining minimum balance", risk.Symbol)
 	}
 
-	logger.Info("sol balance before swap", solBalance)
+	quote := s.selectQuote(ctx, swapAmount)
+	logger.Info("Swapping %s %s (%.0f%% of spendable) to %s (keeping %s %s as reserve)",
+		swapAmount, risk.Symbol, fraction*100, quote.Symbol, reserve, risk.Symbol)
 
-	// Calculate swap amount
-	swapAmount := solBalance - s.config.MinimumSOL
-	if swapAmount <= 0 {
-		return fmt.Errorf("not enough SOL to swap while maintaining minimum balance")
+	// Remember the quote balance so we can attribute the proceeds to this pair
+	quoteBefore, err := s.assetBalance(ctx, quote)
+	if err != nil {
+		return sold, fmt.Errorf("failed to get %s balance: %v", quote.Symbol, err)
 	}
 
-	// Convert the swap amount back to lamports (SOL's smallest unit)
-	lamports := uint64(swapAmount * 1e9)
-	logger.Info("Swapping %.4f SOL to USDC (keeping %.4f SOL as minimum)",
-		swapAmount, s.config.MinimumSOL)
+	sold, err = s.executeSwap(ctx, risk, quote, swapAmount)
+	if sold.IsZero() && err != nil {
+		return sold, fmt.Errorf("failed to perform %s to %s swap: %w", risk.Symbol, quote.Symbol, err)
+	}
 
-	// Use Jupiter swap service with 0.5% slippage (50 basis points)
-	err = s.jupiterSvc.Swap(
-		ctx,
-		SolMint,
-		USDCMint,
-		lamports,
-		5,
-	)
+	// Holdings only accumulate while we keep selling into the same quote asset.
+	// The proceeds of a partially filled order belong to this pair as well.
+	if !quote.Mint.Equals(s.currentQuote.Mint) {
+		s.quoteHolding = nil
+	}
+	s.currentQuote = quote
+	if s.trackHoldings {
+		s.recordQuoteProceeds(ctx, quote, quoteBefore)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to perform SOL to USDC swap: %v", err)
+		return sold, fmt.Errorf("failed to perform %s to %s swap: %w", risk.Symbol, quote.Symbol, err)
+	}
+	return sold, nil
+}
+
+// seedQuoteHolding claims this pair's share of a quote asset shared with other pairs.
+// It runs once, before the first swap; a restarted loop keeps its tracked holding.
+func (s *Service) seedQuoteHolding() error {
+	if !s.trackHoldings || s.quoteHolding != nil {
+		return nil
 	}
 
+	balance, err := s.assetBalance(s.ctx, s.currentQuote)
+	if err != nil {
+		return fmt.Errorf("failed to get %s balance: %v", s.currentQuote.Symbol, err)
+	}
+	sharers := s.quoteSharers[s.currentQuote.Mint.String()]
+	if sharers < 1 {
+		sharers = 1
+	}
+	share := balance.Fraction(1 / float64(sharers))
+	s.quoteHolding = &share
+	logger.Info("%s starts with %s of %s %s shared by %d pairs",
+		s.pairName(), share, balance, s.currentQuote.Symbol, sharers)
 	return nil
 }
 
-// Execute the actual USDC to SOL swap
-func (s *Service) executeUSDCToSOLSwap() error {
+// recordQuoteProceeds adds the quote asset received by the last sell to this pair's holding
+func (s *Service) recordQuoteProceeds(ctx context.Context, quote Asset, before datatypes.TokenAmount) {
+	holding := datatypes.TokenAmount{Decimals: quote.Decimals}
+	if s.quoteHolding != nil {
+		holding = *s.quoteHolding
+	}
+
+	after, err := s.assetBalance(ctx, quote)
+	if err != nil {
+		// Leaving the proceeds unclaimed is safer than claiming the other pairs' balance
+		logger.Warn("Failed to measure %s proceeds, they won't be spent by %s: %v", quote.Symbol, s.pairName(), err)
+		s.quoteHolding = &holding
+		return
+	}
+
+	proceeds := after.Sub(before)
+	proceeds.Raw += holding.Raw
+	s.quoteHolding = &proceeds
+	logger.Info("%s/%s now holds %s %s", s.tokenPair.Risk.Symbol, quote.Symbol, proceeds, quote.Symbol)
+}
+
+// swapFill is the input spent and output received by a swap
+type swapFill struct {
+	In  datatypes.TokenAmount
+	Out datatypes.TokenAmount
+}
+
+// Execute the actual quote to risk swap for a fraction of the held quote asset balance
+func (s *Service) executeQuoteToRiskSwap(fraction float64) error {
+	_, err := s.quoteToRiskFill(fraction)
+	return err
+}
+
+// quoteToRiskFill swaps a fraction of the held quote asset balance to the risk asset and
+// returns the quote amount filled and the risk amount received, also after a partial fill
+func (s *Service) quoteToRiskFill(fraction float64) (swapFill, error) {
 	ctx := context.Background()
+	risk := s.tokenPair.Risk
+	quote := s.currentQuote
+	fill := swapFill{
+		In:  datatypes.TokenAmount{Decimals: quote.Decimals},
+		Out: datatypes.TokenAmount{Decimals: risk.Decimals},
+	}
 
-	// Get current USDC balance
-	tokenBalance, err := s.client.GetTokenAccountBalance(
-		ctx,
-		s.tokenPair.USDCAddress,
-		rpc.CommitmentFinalized,
-	)
+	// Get current quote asset balance
+	balance, err := s.quoteAvailable(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get USDC balance: %v", err)
+		return fill, err
 	}
-	if tokenBalance == nil || tokenBalance.Value == nil {
-		return fmt.Errorf("USDC account not found or empty")
+
+	swapAmount := balance.Fraction(fraction)
+	if swapAmount.IsZero() {
+		return fill, fmt.Errorf("not enough %s to swap", quote.Symbol)
 	}
 
-	// Convert to USDC units
-	usdcAmountStr := tokenBalance.Value.Amount
-	usdcAmount, _ := strconv.ParseFloat(usdcAmountStr, 64)
-	usdcBalance := usdcAmount / 1e6
-	if usdcBalance <= 0 {
-		return fmt.Errorf("not enough USDC to swap")
+	// The risk received is measured from the balance, since only the input of a swap is exact
+	riskBefore, err := s.assetBalance(ctx, risk)
+	if err != nil {
+		return fill, fmt.Errorf("failed to get %s balance: %v", risk.Symbol, err)
 	}
 
-	logger.Info("Swapping %.2f USDC to SOL", usdcBalance)
+	logger.Info("Swapping %s %s (%.0f%% of balance) to %s", swapAmount, quote.Symbol, fraction*100, risk.Symbol)
 
-	// Convert the USDC amount to its smallest unit (6 decimals)
-	usdcLamports, err := strconv.ParseUint(usdcAmountStr, 10, 64)
+	filled, err := s.executeSwap(ctx, quote, risk, swapAmount)
+	fill.In = filled
+	s.spendQuote(filled)
+	if !filled.IsZero() {
+		if riskAfter, balanceErr := s.assetBalance(ctx, risk); balanceErr == nil {
+			fill.Out = riskAfter.Sub(riskBefore)
+		} else {
+			logger.Warn("Failed to measure %s received: %v", risk.Symbol, balanceErr)
+		}
+	}
 	if err != nil {
-		return fmt.Errorf("failed to parse USDC amount: %v", err)
+		return fill, fmt.Errorf("failed to perform %s to %s swap: %w", quote.Symbol, risk.Symbol, err)
+	}
+	return fill, nil
+}
+
+// executeSwap swaps an amount of one asset into another, splitting it into child swaps
+// when it is worth more than MaxSliceValue. It returns the input amount actually swapped,
+// which is less than the requested amount when a sliced order is aborted part way.
+func (s *Service) executeSwap(ctx context.Context, input Asset, output Asset, amount datatypes.TokenAmount) (datatypes.TokenAmount, error) {
+	params := jupiter.SwapParams{
+		InputMint:   input.Mint.String(),
+		OutputMint:  output.Mint.String(),
+		Amount:      amount.Raw,
+		SlippageBps: defaultSlippageBps,
+		Mode:        jupiter.ExactIn,
+
+		ComputeUnitPrice: s.computeUnitPrice(ctx),
 	}
 
-	// Use Jupiter swap service with 0.5% slippage (50 basis points)
-	err = s.jupiterSvc.Swap(
-		ctx,
-		USDCMint,
-		SolMint,
-		usdcLamports,
-		5,
-	)
+	// Every swap is sized in quote units for the minimum trade size and for slicing
+	slices := 1
+	if s.config.FeeCheckEnabled || s.config.MaxSliceValue > 0 {
+		value, err := s.tradeValue(ctx, input, amount)
+		if err != nil {
+			return datatypes.TokenAmount{Decimals: amount.Decimals}, err
+		}
+		if err := s.checkTradeValue(value); err != nil {
+			return datatypes.TokenAmount{Decimals: amount.Decimals}, err
+		}
+		if s.config.MaxSliceValue > 0 {
+			slices = execution.SliceCount(value, s.config.MaxSliceValue)
+		}
+	}
+
+	if slices == 1 {
+		signature, err := s.jupiterSvc.SwapWithParams(ctx, params)
+		if err != nil {
+			return datatypes.TokenAmount{Decimals: amount.Decimals}, err
+		}
+		s.recordExecution(ctx, signature, params.InputMint, params.OutputMint)
+		return amount, nil
+	}
+
+	logger.Info("Splitting %s %s to %s swap into %d slices", amount, input.Symbol, output.Symbol, slices)
+	report, err := s.executor.Execute(ctx, execution.Order{
+		Params:            params,
+		InputDecimals:     input.Decimals,
+		OutputDecimals:    output.Decimals,
+		Slices:            slices,
+		SliceDelay:        time.Duration(s.config.SliceDelay) * time.Second,
+		MaxPriceImpactPct: s.config.MaxPriceImpactPct,
+		MaxPriceMovePct:   s.config.MaxPriceMovePct,
+	})
+	for _, signature := range report.Signatures {
+		s.recordExecution(ctx, signature, params.InputMint, params.OutputMint)
+	}
+	if report.SlicesFilled > 0 {
+		logger.Info("Filled %d/%d slices: %s %s at a quoted average price of %.6g %s per %s",
+			report.SlicesFilled, report.SlicesPlanned, report.InputFilled, input.Symbol,
+			report.QuotedAveragePrice(), output.Symbol, input.Symbol)
+	}
+	return report.InputFilled, err
+}
+
+// quoteAvailable returns the quote asset balance this pair may spend: its tracked
+// holding when it shares the quote asset with other pairs, otherwise the whole balance
+func (s *Service) quoteAvailable(ctx context.Context) (datatypes.TokenAmount, error) {
+	balance, err := s.assetBalance(ctx, s.currentQuote)
 	if err != nil {
-		return fmt.Errorf("failed to perform USDC to SOL swap: %v", err)
+		return datatypes.TokenAmount{}, fmt.Errorf("failed to get %s balance: %v", s.currentQuote.Symbol, err)
 	}
 
-	return nil
+	return s.quoteShare(s.currentQuote, balance), nil
+}
+
+// quoteShare returns the part of a quote asset balance that belongs to this pair. Pairs
+// sharing quote assets only hold their tracked holding of the current quote asset.
+func (s *Service) quoteShare(quote Asset, balance datatypes.TokenAmount) datatypes.TokenAmount {
+	if !s.trackHoldings {
+		return balance
+	}
+	if s.quoteHolding == nil || !quote.Mint.Equals(s.currentQuote.Mint) {
+		return datatypes.TokenAmount{Decimals: balance.Decimals}
+	}
+	if s.quoteHolding.Raw < balance.Raw {
+		return *s.quoteHolding
+	}
+	return balance
+}
+
+// executeExactOutSwap swaps the input asset to receive exactly outAmount of the output asset.
+// The swap only runs when the most it may spend, per a fresh quote including slippage, is
+// within available. It returns the input amount spent, measured from the balance.
+func (s *Service) executeExactOutSwap(ctx context.Context, input Asset, output Asset, outAmount datatypes.TokenAmount, available datatypes.TokenAmount) (datatypes.TokenAmount, error) {
+	none := datatypes.TokenAmount{Decimals: input.Decimals}
+	params := jupiter.SwapParams{
+		InputMint:   input.Mint.String(),
+		OutputMint:  output.Mint.String(),
+		Amount:      outAmount.Raw,
+		SlippageBps: defaultSlippageBps,
+		Mode:        jupiter.ExactOut,
+
+		ComputeUnitPrice: s.computeUnitPrice(ctx),
+	}
+
+	if s.config.FeeCheckEnabled {
+		value, err := s.tradeValue(ctx, output, outAmount)
+		if err != nil {
+			return none, err
+		}
+		if err := s.checkTradeValue(value); err != nil {
+			return none, err
+		}
+	}
+
+	quote, err := s.jupiterSvc.GetQuote(ctx, params)
+	if err != nil {
+		return none, fmt.Errorf("failed to quote ExactOut swap: %v", err)
+	}
+	maxIn, err := exactOutInput(quote, input, available)
+	if err != nil {
+		return none, fmt.Errorf("cannot receive %s %s: %v", outAmount, output.Symbol, err)
+	}
+
+	before, err := s.assetBalance(ctx, input)
+	if err != nil {
+		return none, fmt.Errorf("failed to get %s balance: %v", input.Symbol, err)
+	}
+
+	logger.Info("Swapping up to %s %s for exactly %s %s", maxIn, input.Symbol, outAmount, output.Symbol)
+	signature, err := s.jupiterSvc.SwapWithParams(ctx, params)
+	if err != nil {
+		return none, fmt.Errorf("failed to perform ExactOut swap: %v", err)
+	}
+	s.recordExecution(ctx, signature, params.InputMint, params.OutputMint)
+
+	after, err := s.assetBalance(ctx, input)
+	if err != nil {
+		// Counting the most the swap could have spent never overstates what is left
+		logger.Warn("Failed to measure %s spent, assuming %s: %v", input.Symbol, maxIn, err)
+		return maxIn, nil
+	}
+	return before.Sub(after), nil
+}
+
+// exactOutInput returns the most an ExactOut quote may spend of the input asset, including
+// slippage, and fails when that exceeds the available amount
+func exactOutInput(quote *jupClient.QuoteResponse, input Asset, available datatypes.TokenAmount) (datatypes.TokenAmount, error) {
+	raw, err := strconv.ParseUint(quote.OtherAmountThreshold, 10, 64)
+	if err != nil {
+		return datatypes.TokenAmount{}, fmt.Errorf("invalid maximum input %q: %v", quote.OtherAmountThreshold, err)
+	}
+
+	maxIn := datatypes.TokenAmount{Raw: raw, Decimals: input.Decimals}
+	if maxIn.Raw > available.Raw {
+		return maxIn, fmt.Errorf("needs up to %s %s but only %s %s is available", maxIn, input.Symbol, available, input.Symbol)
+	}
+	return maxIn, nil
+}
+
+// spendQuote removes the quote asset spent by a buy from this pair's tracked holding
+func (s *Service) spendQuote(spent datatypes.TokenAmount) {
+	if s.quoteHolding != nil {
+		remaining := s.quoteHolding.Sub(spent)
+		s.quoteHolding = &remaining
+	}
 }
 
 // handleSwapFailure is a utility function to handle swap failures
 // It determines the current position, gets the latest price, and updates the position state
 func (s *Service) handleSwapFailure(err error, currentPosition *PositionState) error {
+	// A throttled or halted swap never ran, so the position is unchanged
+	if isSkipped(err) {
+		return nil
+	}
+
 	logger.Error("Swap failed: %v", err)
 
 	// If swap failed, determine the current position again
@@ -429,10 +1189,11 @@ func (s *Service) handleSwapFailure(err error, currentPosition *PositionState) e
 
 	// Update the current position based on what we actually have
 	*currentPosition = actualPosition
-	logger.Info("After swap failure, determined current position is: %s", *currentPosition)
+	logger.Info("After swap failure, determined current position is: %s (%s)",
+		*currentPosition, s.positionSymbol(*currentPosition))
 
 	// Get the latest price to make a new decision
-	newPrice, priceErr := s.solanaService.GetSOLPrice(s.ctx)
+	newPrice, priceErr := s.getPairPrice(s.ctx)
 	if priceErr != nil {
 		logger.Error("Failed to get updated price after swap failure: %v", priceErr)
 		return err // Return the original swap error
@@ -440,7 +1201,7 @@ func (s *Service) handleSwapFailure(err error, currentPosition *PositionState) e
 
 	// Recalculate stop loss with the new price
 	newStopLoss := s.calculateDynamicStopLoss(newPrice)
-	logger.Info("Updated SOL price: $%.2f, Updated stop loss: $%.2f", newPrice, newStopLoss)
+	logger.Info("Updated %s price: %.6g, Updated stop loss: %.6g", s.tokenPair.Risk.Symbol, newPrice, newStopLoss)
 
 	// No need to take action here - the next cycle will handle it based on the updated position
 	return nil
@@ -0,0 +1,156 @@
+package swap
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"swap/internal/datatypes"
+	"swap/service/execution"
+	"swap/service/token"
+
+	jupClient "github.com/ilkamo/jupiter-go/jupiter"
+)
+
+// newTestService returns a service for the SOL/USDC pair that never touches the network
+func newTestService(cfg *datatypes.Config) *Service {
+	return &Service{
+		config: cfg,
+		tokenPair: TokenPair{
+			Risk:   Asset{Info: token.Info{Symbol: "SOL", Decimals: 9}, Native: true},
+			Quotes: []Asset{{Info: token.Info{Symbol: "USDC", Decimals: 6}}},
+		},
+		throttle:       newTradeThrottle(),
+		takeProfitHits: make(map[int]bool),
+		walletLock:     &sync.Mutex{},
+	}
+}
+
+func TestAttemptSwapRetries(t *testing.T) {
+	aborted := fmt.Errorf("failed to perform SOL to USDC swap: %w",
+		fmt.Errorf("%w: price moved 3%% since first slice", execution.ErrAborted))
+
+	tests := []struct {
+		name      string
+		errs      []error // Result of each attempt; attempts past the end succeed
+		wantCalls int
+		wantErr   error
+		wantTrade bool
+	}{
+		{name: "success", wantCalls: 1, wantTrade: true},
+		{name: "retried until success", errs: []error{errors.New("blockhash expired")}, wantCalls: 2, wantTrade: true},
+		{name: "aborted order not retried", errs: []error{aborted}, wantCalls: 1, wantErr: execution.ErrAborted, wantTrade: true},
+		{name: "aborted after a failed attempt", errs: []error{errors.New("timeout"), aborted},
+			wantCalls: 2, wantErr: execution.ErrAborted, wantTrade: true},
+		{name: "all attempts fail", errs: []error{errors.New("a"), errors.New("b"), errors.New("c")}, wantCalls: 3},
+		{name: "too small not retried", errs: []error{fmt.Errorf("failed to perform SOL to USDC swap: %w", ErrTradeTooSmall)},
+			wantCalls: 1, wantErr: ErrTradeTooSmall},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(&datatypes.Config{EnableRetry: true, RetryAttempts: 3})
+			calls := 0
+			err := s.attemptSwap(sideSell, func() error {
+				calls++
+				if calls <= len(tt.errs) {
+					return tt.errs[calls-1]
+				}
+				return nil
+			})
+
+			if calls != tt.wantCalls {
+				t.Errorf("swap ran %d times, want %d", calls, tt.wantCalls)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("error %v is not %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && tt.wantTrade && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if traded := len(s.throttle.swaps) > 0; traded != tt.wantTrade {
+				t.Errorf("trade recorded = %t, want %t", traded, tt.wantTrade)
+			}
+		})
+	}
+}
+
+func TestKeepPartialBuy(t *testing.T) {
+	aborted := fmt.Errorf("%w: slice 2 price impact 1.5%% exceeds 1%%", execution.ErrAborted)
+
+	s := newTestService(&datatypes.Config{})
+	s.currentQuote = s.tokenPair.Quotes[0]
+	position := InQuote
+	empty := swapFill{In: datatypes.TokenAmount{Decimals: 6}, Out: datatypes.TokenAmount{Decimals: 9}}
+	if err := s.keepPartialBuy(empty, 150, &position, aborted); !errors.Is(err, execution.ErrAborted) {
+		t.Fatalf("error = %v, want ErrAborted", err)
+	}
+	if position != InQuote || s.entryPrice != 0 {
+		t.Errorf("nothing filled: position %s, entry %.6g; want QUOTE without entry", position, s.entryPrice)
+	}
+
+	partial := swapFill{
+		In:  datatypes.NewTokenAmount(150, 6),
+		Out: datatypes.NewTokenAmount(1, 9),
+	}
+	s.keepPartialBuy(partial, 150, &position, aborted)
+	if position != InRisk || s.entryPrice != 150 {
+		t.Errorf("partial fill: position %s, entry %.6g; want RISK entered at 150", position, s.entryPrice)
+	}
+}
+
+func TestExactOutInput(t *testing.T) {
+	sol := Asset{Info: token.Info{Symbol: "SOL", Decimals: 9}, Native: true}
+
+	tests := []struct {
+		name      string
+		threshold string
+		available uint64
+		wantErr   string
+	}{
+		{name: "within the spendable balance", threshold: "1005000000", available: 2_000_000_000},
+		{name: "exactly the spendable balance", threshold: "1005000000", available: 1_005_000_000},
+		{name: "slippage would dip into the reserve", threshold: "1005000000", available: 1_000_000_000,
+			wantErr: "needs up to 1.005000000 SOL but only 1.000000000 SOL is available"},
+		{name: "invalid threshold", threshold: "", available: 2_000_000_000, wantErr: "invalid maximum input"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote := &jupClient.QuoteResponse{InAmount: "1000000000", OtherAmountThreshold: tt.threshold}
+			available := datatypes.TokenAmount{Raw: tt.available, Decimals: 9}
+
+			maxIn, err := exactOutInput(quote, sol, available)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			case tt.wantErr == "" && maxIn.Raw != 1_005_000_000:
+				t.Errorf("maximum input = %s, want 1.005", maxIn)
+			}
+		})
+	}
+}
+
+func TestSpendQuote(t *testing.T) {
+	s := newTestService(&datatypes.Config{})
+	s.spendQuote(datatypes.NewTokenAmount(10, 6))
+	if s.quoteHolding != nil {
+		t.Fatalf("untracked pair gained a holding: %s", s.quoteHolding)
+	}
+
+	holding := datatypes.NewTokenAmount(100, 6)
+	s.quoteHolding = &holding
+	s.spendQuote(datatypes.NewTokenAmount(30, 6))
+	if s.quoteHolding.Float() != 70 {
+		t.Errorf("holding after spending 30 = %s, want 70", s.quoteHolding)
+	}
+
+	s.spendQuote(datatypes.NewTokenAmount(100, 6))
+	if !s.quoteHolding.IsZero() {
+		t.Errorf("holding after overspending = %s, want 0", s.quoteHolding)
+	}
+}
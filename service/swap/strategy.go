@@ -0,0 +1,121 @@
+package swap
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"swap/internal/datatypes"
+	"swap/pkg/indicators"
+	"swap/pkg/logger"
+)
+
+// Indicator entry filters that can gate buy-backs
+const (
+	// FilterEMACross requires the fast EMA to be above the slow EMA
+	FilterEMACross = "ema_cross"
+
+	// FilterRSI requires the RSI to be below RSIMaxEntry, so overbought spikes aren't chased
+	FilterRSI = "rsi"
+
+	// FilterBollinger requires the close to be between the middle and upper Bollinger band
+	FilterBollinger = "bollinger"
+)
+
+// validateEntryFilters rejects unknown filter names and the parameters the configured
+// filters can't work with. An invalid filter would otherwise hold back every buy-back.
+func validateEntryFilters(cfg *datatypes.Config) error {
+	for _, filter := range cfg.EntryFilters {
+		switch strings.TrimSpace(filter) {
+		case FilterEMACross:
+			if cfg.FastEMAPeriod <= 0 || cfg.SlowEMAPeriod <= cfg.FastEMAPeriod {
+				return fmt.Errorf("%s filter needs 0 < fast EMA period < slow EMA period, got %d and %d",
+					FilterEMACross, cfg.FastEMAPeriod, cfg.SlowEMAPeriod)
+			}
+		case FilterRSI:
+			if cfg.RSIPeriod <= 0 {
+				return fmt.Errorf("%s filter needs a positive period, got %d", FilterRSI, cfg.RSIPeriod)
+			}
+			if cfg.RSIMaxEntry <= 0 || cfg.RSIMaxEntry > 100 {
+				return fmt.Errorf("%s filter needs a maximum entry RSI in (0, 100], got %.2f", FilterRSI, cfg.RSIMaxEntry)
+			}
+		case FilterBollinger:
+			if cfg.BollingerPeriod < 2 {
+				return fmt.Errorf("%s filter needs a period of at least 2, got %d", FilterBollinger, cfg.BollingerPeriod)
+			}
+			if cfg.BollingerStdDev <= 0 {
+				return fmt.Errorf("%s filter needs a positive band width, got %.2f", FilterBollinger, cfg.BollingerStdDev)
+			}
+		default:
+			return fmt.Errorf("unknown entry filter %q: use %s, %s or %s", filter, FilterEMACross, FilterRSI, FilterBollinger)
+		}
+	}
+	return nil
+}
+
+// newCandleAggregator creates the candle series used by the indicator filters,
+// keeping enough candles for the longest configured indicator
+func newCandleAggregator(periodSeconds int, lookback ...int) *indicators.Aggregator {
+	if periodSeconds <= 0 {
+		periodSeconds = 60
+	}
+	limit := 0
+	for _, n := range lookback {
+		if n > limit {
+			limit = n
+		}
+	}
+	return indicators.NewAggregator(time.Duration(periodSeconds)*time.Second, limit*4+1)
+}
+
+// entryAllowed checks every configured indicator filter against the closed candles.
+// Buy-backs are held back until all filters pass; filters without enough candles fail.
+func (s *Service) entryAllowed() bool {
+	if len(s.config.EntryFilters) == 0 {
+		return true
+	}
+
+	closes := indicators.Closes(s.candles.Candles())
+	for _, filter := range s.config.EntryFilters {
+		ok, detail := s.checkFilter(strings.TrimSpace(filter), closes)
+		if !ok {
+			logger.Info("Buy-back held back by %s filter: %s", filter, detail)
+			return false
+		}
+		logger.Debug("Entry filter %s passed: %s", filter, detail)
+	}
+	return true
+}
+
+// checkFilter evaluates a single indicator filter and describes the values it saw
+func (s *Service) checkFilter(filter string, closes []float64) (bool, string) {
+	switch filter {
+	case FilterEMACross:
+		fast, fastOK := indicators.EMA(closes, s.config.FastEMAPeriod)
+		slow, slowOK := indicators.EMA(closes, s.config.SlowEMAPeriod)
+		if !fastOK || !slowOK {
+			return false, fmt.Sprintf("%d/%d candles collected", len(closes), s.config.SlowEMAPeriod)
+		}
+		return fast > slow, fmt.Sprintf("EMA%d %.6g vs EMA%d %.6g",
+			s.config.FastEMAPeriod, fast, s.config.SlowEMAPeriod, slow)
+
+	case FilterRSI:
+		rsi, ok := indicators.RSI(closes, s.config.RSIPeriod)
+		if !ok {
+			return false, fmt.Sprintf("%d/%d candles collected", len(closes), s.config.RSIPeriod+1)
+		}
+		return rsi < s.config.RSIMaxEntry, fmt.Sprintf("RSI%d %.2f, max %.2f", s.config.RSIPeriod, rsi, s.config.RSIMaxEntry)
+
+	case FilterBollinger:
+		bands, ok := indicators.Bollinger(closes, s.config.BollingerPeriod, s.config.BollingerStdDev)
+		if !ok {
+			return false, fmt.Sprintf("%d/%d candles collected", len(closes), s.config.BollingerPeriod)
+		}
+		last := closes[len(closes)-1]
+		return last > bands.Middle && last < bands.Upper, fmt.Sprintf("close %.6g, bands %.6g/%.6g/%.6g",
+			last, bands.Lower, bands.Middle, bands.Upper)
+
+	default:
+		return false, fmt.Sprintf("unknown filter %q", filter)
+	}
+}
@@ -0,0 +1,65 @@
+package swap
+
+import (
+	"strings"
+	"testing"
+
+	"swap/internal/datatypes"
+)
+
+func TestValidateEntryFilters(t *testing.T) {
+	valid := func() *datatypes.Config {
+		return &datatypes.Config{
+			FastEMAPeriod:   20,
+			SlowEMAPeriod:   50,
+			RSIPeriod:       14,
+			RSIMaxEntry:     70,
+			BollingerPeriod: 20,
+			BollingerStdDev: 2,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		filters []string
+		modify  func(*datatypes.Config)
+		wantErr string
+	}{
+		{name: "no filters"},
+		{name: "all filters", filters: []string{FilterEMACross, " " + FilterRSI, FilterBollinger}},
+		{name: "unknown filter", filters: []string{FilterRSI, "macd"}, wantErr: `unknown entry filter "macd"`},
+		{name: "misspelled filter", filters: []string{"ema-cross"}, wantErr: "unknown entry filter"},
+		{name: "unused parameters ignored", filters: []string{FilterRSI},
+			modify: func(cfg *datatypes.Config) { cfg.SlowEMAPeriod = 0 }},
+		{name: "slow EMA not slower", filters: []string{FilterEMACross},
+			modify: func(cfg *datatypes.Config) { cfg.SlowEMAPeriod = 20 }, wantErr: "slow EMA period"},
+		{name: "zero RSI period", filters: []string{FilterRSI},
+			modify: func(cfg *datatypes.Config) { cfg.RSIPeriod = 0 }, wantErr: "positive period"},
+		{name: "RSI threshold above 100", filters: []string{FilterRSI},
+			modify: func(cfg *datatypes.Config) { cfg.RSIMaxEntry = 170 }, wantErr: "maximum entry RSI"},
+		{name: "single candle Bollinger", filters: []string{FilterBollinger},
+			modify: func(cfg *datatypes.Config) { cfg.BollingerPeriod = 1 }, wantErr: "at least 2"},
+		{name: "zero Bollinger width", filters: []string{FilterBollinger},
+			modify: func(cfg *datatypes.Config) { cfg.BollingerStdDev = 0 }, wantErr: "band width"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			cfg.EntryFilters = tt.filters
+			if tt.modify != nil {
+				tt.modify(cfg)
+			}
+
+			err := validateEntryFilters(cfg)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.wantErr != "" && err == nil:
+				t.Fatalf("expected error containing %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Fatalf("error %q does not contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package swap
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"swap/internal/datatypes"
+	"swap/internal/utils"
+	"swap/pkg/logger"
+)
+
+// Re-entry behaviors after a full take-profit exit
+const (
+	// ReentryPullback buys back once the price pulls back ReentryPullbackPct below the exit price
+	ReentryPullback = "pullback"
+
+	// ReentryBreakout buys back once the price rises above the exit price
+	ReentryBreakout = "breakout"
+
+	// ReentryNever stays in the quote asset until the stop loss logic takes over again
+	ReentryNever = "never"
+)
+
+// TakeProfitState is the persisted take-profit progress since the last entry
+type TakeProfitState struct {
+	EntryPrice float64                     `json:"entryPrice"`
+	Hits       []datatypes.TakeProfitLevel `json:"hits"`      // Levels that fired since the entry
+	ExitPrice  float64                     `json:"exitPrice"` // Full take-profit exit awaiting re-entry, 0 if none
+}
+
+// validateTakeProfitLevels rejects take-profit levels that could never fire as intended
+func validateTakeProfitLevels(cfg *datatypes.Config) error {
+	for i, level := range cfg.TakeProfitLevels {
+		if level.Price <= 0 && level.PercentAboveEntry <= 0 {
+			return fmt.Errorf("take-profit level %d needs a price or a positive percent above entry", i+1)
+		}
+		if level.Fraction <= 0 || level.Fraction > 1 {
+			return fmt.Errorf("take-profit level %d needs a share to sell in (0, 1], got %.4f", i+1, level.Fraction)
+		}
+	}
+
+	switch cfg.TakeProfitReentry {
+	case "", ReentryPullback, ReentryBreakout, ReentryNever:
+		return nil
+	default:
+		return fmt.Errorf("unknown take-profit re-entry %q: use %s, %s or %s",
+			cfg.TakeProfitReentry, ReentryPullback, ReentryBreakout, ReentryNever)
+	}
+}
+
+// takeProfitTrigger returns the price at which a take-profit level fires, or 0 when
+// it is relative to an entry price that isn't known
+func (s *Service) takeProfitTrigger(level datatypes.TakeProfitLevel) float64 {
+	if level.Price > 0 {
+		return level.Price
+	}
+	if s.entryPrice <= 0 {
+		return 0
+	}
+	return s.entryPrice * (1 + level.PercentAboveEntry/100)
+}
+
+// checkTakeProfit sells into strength when the price reaches a take-profit level that hasn't
+// fired since the last entry. It returns true when a level was handled this cycle.
+func (s *Service) checkTakeProfit(price float64, currentPosition *PositionState) (bool, error) {
+	for i, level := range s.config.TakeProfitLevels {
+		trigger := s.takeProfitTrigger(level)
+		if trigger <= 0 || price < trigger || s.takeProfitHits[i] {
+			continue
+		}
+
+		fraction := level.Fraction
+		logger.Info("Take profit %d triggered at %.6g %s (level %.6g)! Selling %.0f%% of %s",
+			i+1, price, s.currentQuote.Symbol, trigger, fraction*100, s.tokenPair.Risk.Symbol)
+		sold, err := s.sellRiskFill(fraction)
+		if isAborted(err) {
+			// Part of the level was sold; firing it again would sell a fraction of the rest
+			if !sold.IsZero() {
+				s.takeProfitHits[i] = true
+				s.saveTakeProfitState()
+			}
+			logger.Warn("Take profit %d aborted after selling %s %s, keeping the rest under the stop: %v",
+				i+1, sold, s.tokenPair.Risk.Symbol, err)
+			return true, err
+		}
+		if err != nil {
+			return true, s.handleSwapFailure(err, currentPosition)
+		}
+		s.takeProfitHits[i] = true
+
+		// A partial exit keeps the rest of the position under the trailing stop
+		if fraction < 1 {
+			s.saveTakeProfitState()
+			return true, nil
+		}
+
+		*currentPosition = InQuote
+		s.takeProfitExit = price
+		s.saveTakeProfitState()
+		s.resetLadder()
+		logger.Info("Exited %s at %.6g %s on take profit, re-entry mode: %s",
+			s.tokenPair.Risk.Symbol, price, s.currentQuote.Symbol, s.reentryMode())
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// reentryMode returns the configured re-entry behavior, defaulting to a pullback
+func (s *Service) reentryMode() string {
+	if s.config.TakeProfitReentry == "" {
+		return ReentryPullback
+	}
+	return s.config.TakeProfitReentry
+}
+
+// takeProfitReentry decides whether to buy back after a full take-profit exit. Falling
+// below the stop loss hands control back to the regular stop loss re-entry.
+func (s *Service) takeProfitReentry(price float64, effectiveStopLoss float64, currentPosition *PositionState) error {
+	if price < effectiveStopLoss {
+		logger.Info("Price fell below the stop loss after take profit, resuming regular re-entry")
+		s.takeProfitExit = 0
+		s.saveTakeProfitState()
+		return nil
+	}
+
+	var reenter bool
+	switch s.reentryMode() {
+	case ReentryBreakout:
+		reenter = price > s.takeProfitExit
+	case ReentryNever:
+		reenter = false
+	default:
+		reenter = price <= s.takeProfitExit*(1-s.config.ReentryPullbackPct/100)
+	}
+	if !reenter {
+		return nil
+	}
+
+	logger.Info("Re-entering %s at %.6g %s after take profit at %.6g",
+		s.tokenPair.Risk.Symbol, price, s.currentQuote.Symbol, s.takeProfitExit)
+	fill, err := s.swapQuoteToRisk()
+	if isAborted(err) {
+		return s.keepPartialBuy(fill, price, currentPosition, err)
+	}
+	if err != nil {
+		return s.handleSwapFailure(err, currentPosition)
+	}
+	*currentPosition = InRisk
+	s.markEntry(price)
+	return nil
+}
+
+// markEntry records a new entry into the risk asset and re-arms the take-profit levels.
+// The trailing stop keeps following HighestPrice across entries.
+func (s *Service) markEntry(price float64) {
+	s.entryPrice = price
+	s.takeProfitExit = 0
+	s.takeProfitHits = make(map[int]bool)
+	s.saveTakeProfitState()
+}
+
+// loadTakeProfitState restores the entry price and take-profit progress persisted by a
+// previous run, so a restart neither re-fires levels nor re-bases them on the current price.
+// Hits of levels that are no longer configured are dropped.
+func (s *Service) loadTakeProfitState() {
+	path := s.stateFile("takeprofit")
+	if path == "" {
+		return
+	}
+
+	var state TakeProfitState
+	if err := utils.ReadJSONFile(path, &state); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Warn("Failed to load take-profit state from %s: %v", path, err)
+		}
+		return
+	}
+
+	s.entryPrice = state.EntryPrice
+	s.takeProfitExit = state.ExitPrice
+	s.takeProfitHits = make(map[int]bool)
+	for _, hit := range state.Hits {
+		for i, level := range s.config.TakeProfitLevels {
+			if level == hit {
+				s.takeProfitHits[i] = true
+			}
+		}
+	}
+	logger.Info("Resuming take profit: entry %.6g, %d levels hit, exit %.6g",
+		s.entryPrice, len(s.takeProfitHits), s.takeProfitExit)
+}
+
+// saveTakeProfitState persists the entry price and take-profit progress so they survive restarts
+func (s *Service) saveTakeProfitState() {
+	path := s.stateFile("takeprofit")
+	if path == "" || len(s.config.TakeProfitLevels) == 0 {
+		return
+	}
+
+	state := TakeProfitState{EntryPrice: s.entryPrice, ExitPrice: s.takeProfitExit}
+	for i, level := range s.config.TakeProfitLevels {
+		if s.takeProfitHits[i] {
+			state.Hits = append(state.Hits, level)
+		}
+	}
+	if err := utils.WriteJSONFile(path, state); err != nil {
+		logger.Warn("Failed to save take-profit state to %s: %v", path, err)
+	}
+}
@@ -0,0 +1,85 @@
+package swap
+
+import (
+	"strings"
+	"testing"
+
+	"swap/internal/datatypes"
+)
+
+func TestValidateTakeProfitLevels(t *testing.T) {
+	tests := []struct {
+		name    string
+		levels  []datatypes.TakeProfitLevel
+		reentry string
+		wantErr string
+	}{
+		{name: "no levels"},
+		{name: "percent and price levels", levels: []datatypes.TakeProfitLevel{
+			{PercentAboveEntry: 10, Fraction: 0.5}, {Price: 250, Fraction: 1}}, reentry: ReentryBreakout},
+		{name: "no trigger", levels: []datatypes.TakeProfitLevel{{Fraction: 0.5}}, wantErr: "level 1 needs a price"},
+		{name: "negative percent", levels: []datatypes.TakeProfitLevel{{PercentAboveEntry: -5, Fraction: 1}},
+			wantErr: "positive percent"},
+		{name: "nothing to sell", levels: []datatypes.TakeProfitLevel{{PercentAboveEntry: 10, Fraction: 1},
+			{PercentAboveEntry: 20}}, wantErr: "level 2 needs a share to sell"},
+		{name: "more than the position", levels: []datatypes.TakeProfitLevel{{PercentAboveEntry: 10, Fraction: 50}},
+			wantErr: "share to sell"},
+		{name: "unknown re-entry", reentry: "dip", wantErr: `unknown take-profit re-entry "dip"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTakeProfitLevels(&datatypes.Config{TakeProfitLevels: tt.levels, TakeProfitReentry: tt.reentry})
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTakeProfitStatePersistence(t *testing.T) {
+	levels := []datatypes.TakeProfitLevel{
+		{PercentAboveEntry: 10, Fraction: 0.5},
+		{PercentAboveEntry: 20, Fraction: 0.5},
+		{PercentAboveEntry: 30, Fraction: 1},
+	}
+	cfg := &datatypes.Config{StateDir: t.TempDir(), TakeProfitLevels: levels}
+
+	s := newTestService(cfg)
+	s.markEntry(150)
+	s.takeProfitHits[0] = true
+	s.takeProfitHits[1] = true
+	s.takeProfitExit = 180
+	s.saveTakeProfitState()
+
+	restarted := newTestService(cfg)
+	restarted.loadTakeProfitState()
+	if restarted.entryPrice != 150 || restarted.takeProfitExit != 180 {
+		t.Errorf("restored entry %.6g and exit %.6g, want 150 and 180", restarted.entryPrice, restarted.takeProfitExit)
+	}
+	if !restarted.takeProfitHits[0] || !restarted.takeProfitHits[1] || restarted.takeProfitHits[2] {
+		t.Errorf("restored hits %v, want levels 1 and 2", restarted.takeProfitHits)
+	}
+
+	// A changed level isn't considered hit
+	changed := &datatypes.Config{StateDir: cfg.StateDir, TakeProfitLevels: []datatypes.TakeProfitLevel{
+		{PercentAboveEntry: 15, Fraction: 0.5}, levels[1],
+	}}
+	reconfigured := newTestService(changed)
+	reconfigured.loadTakeProfitState()
+	if reconfigured.takeProfitHits[0] || !reconfigured.takeProfitHits[1] {
+		t.Errorf("restored hits %v after changing level 1, want only level 2", reconfigured.takeProfitHits)
+	}
+
+	// A new entry re-arms the levels for the next run too
+	s.markEntry(160)
+	restarted = newTestService(cfg)
+	restarted.loadTakeProfitState()
+	if restarted.entryPrice != 160 || len(restarted.takeProfitHits) != 0 || restarted.takeProfitExit != 0 {
+		t.Errorf("after a new entry restored entry %.6g, hits %v, exit %.6g; want 160 without hits",
+			restarted.entryPrice, restarted.takeProfitHits, restarted.takeProfitExit)
+	}
+}
@@ -0,0 +1,179 @@
+package swap
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"swap/internal/utils"
+	"swap/pkg/logger"
+	"swap/service/risk"
+)
+
+// ErrTradeThrottled is returned when a swap is skipped by the trade frequency limits
+var ErrTradeThrottled = errors.New("trade throttled")
+
+// tradeSide is the direction of a swap relative to the risk asset
+type tradeSide string
+
+const (
+	sideBuy  tradeSide = "buy"
+	sideSell tradeSide = "sell"
+)
+
+// Reasons a swap can be throttled
+const (
+	throttleCooldown    = "cooldown"
+	throttleHourlyLimit = "hourly limit"
+	throttleDailyLimit  = "daily limit"
+	throttleMinHold     = "minimum hold time"
+)
+
+// tradeThrottle enforces the cooldown, rolling swap limits and minimum hold time
+// and counts how often each of them skipped a swap
+type tradeThrottle struct {
+	mu      sync.Mutex
+	swaps   []time.Time // Successful swaps within the last day
+	lastBuy time.Time
+	skipped map[string]int
+}
+
+// ThrottleState is the persisted swap history the trade frequency limits are based on
+type ThrottleState struct {
+	Swaps   []time.Time `json:"swaps"` // Successful swaps within the last day
+	LastBuy time.Time   `json:"lastBuy"`
+}
+
+// newTradeThrottle creates an empty trade throttle
+func newTradeThrottle() *tradeThrottle {
+	return &tradeThrottle{skipped: make(map[string]int)}
+}
+
+// checkThrottle returns the reason a swap on the given side may not run now, or "" when it may
+func (s *Service) checkThrottle(side tradeSide, now time.Time) string {
+	t := s.throttle
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Forget swaps older than the longest window
+	cutoff := now.Add(-24 * time.Hour)
+	for len(t.swaps) > 0 && t.swaps[0].Before(cutoff) {
+		t.swaps = t.swaps[1:]
+	}
+
+	reason := ""
+	switch {
+	case s.config.SwapCooldown > 0 && len(t.swaps) > 0 &&
+		now.Sub(t.swaps[len(t.swaps)-1]) < time.Duration(s.config.SwapCooldown)*time.Second:
+		reason = throttleCooldown
+	case side == sideSell && s.config.MinHoldTime > 0 && !t.lastBuy.IsZero() &&
+		now.Sub(t.lastBuy) < time.Duration(s.config.MinHoldTime)*time.Second:
+		reason = throttleMinHold
+	case s.config.MaxSwapsPerHour > 0 && countSince(t.swaps, now.Add(-time.Hour)) >= s.config.MaxSwapsPerHour:
+		reason = throttleHourlyLimit
+	case s.config.MaxSwapsPerDay > 0 && len(t.swaps) >= s.config.MaxSwapsPerDay:
+		reason = throttleDailyLimit
+	}
+
+	if reason != "" {
+		t.skipped[reason]++
+		logger.Info("Skipping %s: %s (%d swaps skipped for this reason so far)", side, reason, t.skipped[reason])
+	}
+	return reason
+}
+
+// recordTrade registers a successful swap with the throttle
+func (s *Service) recordTrade(side tradeSide, now time.Time) {
+	t := s.throttle
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.swaps = append(t.swaps, now)
+	if side == sideBuy {
+		t.lastBuy = now
+	}
+	s.saveThrottleState(ThrottleState{Swaps: t.swaps, LastBuy: t.lastBuy})
+}
+
+// loadThrottleState restores the swaps recorded by a previous run, so a restart
+// doesn't reset the cooldown, the swap limits or the minimum hold time
+func (s *Service) loadThrottleState() {
+	path := s.stateFile("throttle")
+	if path == "" {
+		return
+	}
+
+	var state ThrottleState
+	if err := utils.ReadJSONFile(path, &state); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logger.Warn("Failed to load throttle state from %s: %v", path, err)
+		}
+		return
+	}
+
+	t := s.throttle
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.swaps = state.Swaps
+	t.lastBuy = state.LastBuy
+	if len(t.swaps) > 0 {
+		logger.Info("Resuming trade limits: %d swaps recorded, last at %s",
+			len(t.swaps), t.swaps[len(t.swaps)-1].Format(time.RFC3339))
+	}
+}
+
+// saveThrottleState persists the recorded swaps so the trade limits survive restarts
+func (s *Service) saveThrottleState(state ThrottleState) {
+	path := s.stateFile("throttle")
+	if path == "" {
+		return
+	}
+	if err := utils.WriteJSONFile(path, state); err != nil {
+		logger.Warn("Failed to save throttle state to %s: %v", path, err)
+	}
+}
+
+// SkippedTrades returns how many swaps were skipped for each throttle reason
+func (s *Service) SkippedTrades() map[string]int {
+	t := s.throttle
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	counts := make(map[string]int, len(t.skipped))
+	for reason, count := range t.skipped {
+		counts[reason] = count
+	}
+	return counts
+}
+
+// throttled wraps a throttle reason into an ErrTradeThrottled error
+func throttled(reason string) error {
+	return fmt.Errorf("%w: %s", ErrTradeThrottled, reason)
+}
+
+// isSkipped reports whether a swap was skipped before running, by the trade frequency
+// limits, the minimum trade size or the risk circuit breaker
+func isSkipped(err error) bool {
+	return errors.Is(err, ErrTradeThrottled) || errors.Is(err, ErrTradeTooSmall) || errors.Is(err, risk.ErrHalted)
+}
+
+// ignoreSkipped drops errors of swaps that were skipped, which have already been logged
+func ignoreSkipped(err error) error {
+	if isSkipped(err) {
+		return nil
+	}
+	return err
+}
+
+// countSince returns the number of times at or after since
+func countSince(times []time.Time, since time.Time) int {
+	count := 0
+	for _, t := range times {
+		if !t.Before(since) {
+			count++
+		}
+	}
+	return count
+}
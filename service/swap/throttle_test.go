@@ -0,0 +1,47 @@
+package swap
+
+import (
+	"testing"
+	"time"
+
+	"swap/internal/datatypes"
+)
+
+func TestThrottleSurvivesRestart(t *testing.T) {
+	cfg := &datatypes.Config{
+		StateDir:        t.TempDir(),
+		SwapCooldown:    60,
+		MaxSwapsPerHour: 2,
+		MinHoldTime:     600,
+	}
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	s := newTestService(cfg)
+	s.recordTrade(sideSell, now.Add(-50*time.Minute))
+	s.recordTrade(sideBuy, now.Add(-5*time.Minute))
+
+	restarted := newTestService(cfg)
+	if reason := restarted.checkThrottle(sideBuy, now); reason != "" {
+		t.Fatalf("fresh throttle skipped the swap: %s", reason)
+	}
+	restarted.loadThrottleState()
+
+	tests := []struct {
+		name string
+		side tradeSide
+		at   time.Time
+		want string
+	}{
+		{"cooldown after the last swap", sideBuy, now.Add(-270 * time.Second), throttleCooldown},
+		{"minimum hold after the last buy", sideSell, now, throttleMinHold},
+		{"hourly limit", sideBuy, now, throttleHourlyLimit},
+		{"limits reset once the swaps age out", sideBuy, now.Add(11 * time.Minute), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if reason := restarted.checkThrottle(tt.side, tt.at); reason != tt.want {
+				t.Errorf("checkThrottle = %q, want %q", reason, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package swap
+
+import (
+	"time"
+
+	"swap/pkg/indicators"
+	"swap/pkg/logger"
+)
+
+// Volatility measures for the adaptive stop distance
+const (
+	// VolatilityATR uses the average true range of the volatility candles
+	VolatilityATR = "atr"
+
+	// VolatilityStdDev uses the standard deviation of the volatility candles' closes
+	VolatilityStdDev = "stddev"
+)
+
+// Defaults used when the volatility candles aren't configured
+const (
+	defaultVolatilityWindow       = 14
+	defaultVolatilityCandlePeriod = 300
+)
+
+// newVolatilityAggregator creates the candle series the adaptive stop distance is measured over
+func newVolatilityAggregator(periodSeconds int, window int) *indicators.Aggregator {
+	if periodSeconds <= 0 {
+		periodSeconds = defaultVolatilityCandlePeriod
+	}
+	if window <= 0 {
+		window = defaultVolatilityWindow
+	}
+	return indicators.NewAggregator(time.Duration(periodSeconds)*time.Second, window+1)
+}
+
+// volatility measures the configured volatility over the closed volatility candles.
+// ok is false until enough candles have closed.
+func (s *Service) volatility() (float64, bool) {
+	window := s.config.VolatilityWindow
+	if window <= 0 {
+		window = defaultVolatilityWindow
+	}
+
+	candles := s.volCandles.Candles()
+	if s.config.VolatilityMethod == VolatilityStdDev {
+		return indicators.StdDev(indicators.Closes(candles), window)
+	}
+	return indicators.ATR(candles, window)
+}
+
+// clampStopDistance limits a stop distance to between minPct and maxPct percent of the
+// price. A zero bound is not applied.
+func clampStopDistance(distance float64, price float64, minPct float64, maxPct float64) float64 {
+	if minPct > 0 && distance < price*minPct/100 {
+		distance = price * minPct / 100
+	}
+	if maxPct > 0 && distance > price*maxPct/100 {
+		distance = price * maxPct / 100
+	}
+	return distance
+}
+
+// stopDistance returns how far the trailing stop sits below the highest price. With the
+// volatility stop enabled it is a multiple of the volatility of recent candles, clamped to
+// a percentage range of the price; until enough candles have closed the fixed
+// StopLossAdjustment is used.
+func (s *Service) stopDistance(price float64) float64 {
+	if !s.config.VolatilityStop || s.volCandles == nil {
+		return s.config.StopLossAdjustment
+	}
+
+	volatility, ok := s.volatility()
+	if !ok {
+		return s.config.StopLossAdjustment
+	}
+
+	distance := clampStopDistance(volatility*s.config.VolatilityMultiplier, price,
+		s.config.MinStopPct, s.config.MaxStopPct)
+
+	logger.Debug("Adaptive stop distance: %.6g (%s volatility %.6g x %.2f, clamped to %.2f-%.2f%% of %.6g)",
+		distance, s.config.VolatilityMethod, volatility, s.config.VolatilityMultiplier,
+		s.config.MinStopPct, s.config.MaxStopPct, price)
+	return distance
+}
@@ -0,0 +1,126 @@
+package swap
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"swap/internal/datatypes"
+)
+
+// tickSeries simulates polled SOL prices: a random walk around $150 sampled every 2 seconds
+// with moves of a few cents per tick, as seen on a quiet to moderately active day
+func tickSeries(start time.Time, duration time.Duration) ([]time.Time, []float64) {
+	rng := rand.New(rand.NewSource(42))
+	price := 150.0
+	var times []time.Time
+	var prices []float64
+	for t := start; t.Before(start.Add(duration)); t = t.Add(2 * time.Second) {
+		price += rng.NormFloat64() * 0.03
+		times = append(times, t)
+		prices = append(prices, price)
+	}
+	return times, prices
+}
+
+func volatilityConfig(method string) *datatypes.Config {
+	return &datatypes.Config{
+		StopLossAdjustment:     5,
+		VolatilityStop:         true,
+		VolatilityMethod:       method,
+		VolatilityCandlePeriod: 300,
+		VolatilityWindow:       14,
+		VolatilityMultiplier:   3,
+		MinStopPct:             0.5,
+		MaxStopPct:             10,
+	}
+}
+
+func TestStopDistanceOnTickData(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	times, prices := tickSeries(start, 3*time.Hour)
+	last := prices[len(prices)-1]
+	minDistance, maxDistance := last*0.5/100, last*10/100
+
+	// Averaging the moves between ticks measures noise, not the swings a stop has to survive
+	tickMoves := 0.0
+	for i := 1; i < len(prices); i++ {
+		tickMoves += math.Abs(prices[i] - prices[i-1])
+	}
+	if perTick := tickMoves / float64(len(prices)-1) * 3; perTick >= minDistance {
+		t.Fatalf("tick data too volatile for the scenario: 3x average tick move %.4f", perTick)
+	}
+
+	for _, method := range []string{VolatilityATR, VolatilityStdDev} {
+		t.Run(method, func(t *testing.T) {
+			cfg := volatilityConfig(method)
+			s := newTestService(cfg)
+			s.volCandles = newVolatilityAggregator(cfg.VolatilityCandlePeriod, cfg.VolatilityWindow)
+
+			for i := range prices {
+				s.volCandles.Add(times[i], prices[i])
+			}
+
+			distance := s.stopDistance(last)
+			if distance <= minDistance || distance >= maxDistance {
+				t.Errorf("stop distance %.4f at %.2f not between the clamps %.4f and %.4f",
+					distance, last, minDistance, maxDistance)
+			}
+			if distance == cfg.StopLossAdjustment {
+				t.Errorf("stop distance is the fixed adjustment, volatility wasn't used")
+			}
+		})
+	}
+}
+
+func TestStopDistanceWarmUp(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cfg := volatilityConfig(VolatilityATR)
+	s := newTestService(cfg)
+	s.volCandles = newVolatilityAggregator(cfg.VolatilityCandlePeriod, cfg.VolatilityWindow)
+
+	// 14 closed candles are one short of the 15 the true range needs
+	times, prices := tickSeries(start, 15*5*time.Minute)
+	for i := range prices {
+		s.volCandles.Add(times[i], prices[i])
+	}
+	if got := s.stopDistance(150); got != cfg.StopLossAdjustment {
+		t.Errorf("stop distance during warm-up = %.4f, want the fixed %.4f", got, cfg.StopLossAdjustment)
+	}
+
+	s.volCandles.Add(start.Add(16*5*time.Minute), 150)
+	if got := s.stopDistance(150); got == cfg.StopLossAdjustment {
+		t.Errorf("stop distance still fixed once 15 candles closed")
+	}
+
+	cfg.VolatilityStop = false
+	if got := s.stopDistance(150); got != cfg.StopLossAdjustment {
+		t.Errorf("stop distance with the volatility stop disabled = %.4f, want %.4f", got, cfg.StopLossAdjustment)
+	}
+}
+
+func TestClampStopDistance(t *testing.T) {
+	tests := []struct {
+		name     string
+		distance float64
+		price    float64
+		minPct   float64
+		maxPct   float64
+		want     float64
+	}{
+		{"within bounds", 3, 150, 0.5, 10, 3},
+		{"raised to the minimum", 0.1, 150, 0.5, 10, 0.75},
+		{"capped at the maximum", 30, 150, 0.5, 10, 15},
+		{"scales with the price", 0.00000001, 0.00002, 0.5, 10, 0.0000001},
+		{"no bounds", 30, 150, 0, 0, 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := clampStopDistance(tt.distance, tt.price, tt.minPct, tt.maxPct)
+			if math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("clampStopDistance = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
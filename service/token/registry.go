@@ -0,0 +1,130 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// mintDecimalsOffset is the byte offset of the decimals field in an SPL mint account.
+// Token-2022 mints share the same base layout.
+const mintDecimalsOffset = 44
+
+// KnownMints maps well-known token symbols to their mainnet mint addresses
+var KnownMints = map[string]string{
+	"SOL":   WrappedSOLMint,
+	"USDC":  "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v",
+	"USDT":  "Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB",
+	"PYUSD": "2b1kV6DkPAnxd5ixfnxCpjxmKwqjjaYmCZfHsFu24GXo",
+}
+
+// Info describes a token mint
+type Info struct {
+	Symbol       string
+	Mint         solana.PublicKey
+	Decimals     uint8
+	TokenProgram solana.PublicKey
+}
+
+// Registry resolves token symbols and mints to their on-chain mint information
+type Registry struct {
+	client *rpc.Client
+	mu     sync.Mutex
+	tokens map[string]Info
+}
+
+// NewRegistry creates a new token registry
+func NewRegistry(client *rpc.Client) *Registry {
+	return &Registry{
+		client: client,
+		tokens: make(map[string]Info),
+	}
+}
+
+// Resolve looks up a token by symbol (e.g. "USDT") or mint address, reading its
+// decimals and token program from the on-chain mint account. Results are cached.
+func (r *Registry) Resolve(ctx context.Context, symbolOrMint string) (Info, error) {
+	symbol, mintStr := lookupSymbol(symbolOrMint)
+
+	r.mu.Lock()
+	if info, ok := r.tokens[mintStr]; ok {
+		r.mu.Unlock()
+		return info, nil
+	}
+	r.mu.Unlock()
+
+	mint, err := solana.PublicKeyFromBase58(mintStr)
+	if err != nil {
+		return Info{}, fmt.Errorf("unknown token %q: %v", symbolOrMint, err)
+	}
+
+	account, err := r.client.GetAccountInfo(ctx, mint)
+	if err != nil {
+		if errors.Is(err, rpc.ErrNotFound) {
+			return Info{}, fmt.Errorf("mint %s: %w", mint, ErrAccountNotFound)
+		}
+		return Info{}, fmt.Errorf("failed to get mint account %s: %v", mint, err)
+	}
+
+	owner := account.Value.Owner
+	if !owner.Equals(solana.TokenProgramID) && !owner.Equals(solana.Token2022ProgramID) {
+		return Info{}, fmt.Errorf("mint %s is owned by unsupported program %s", mint, owner)
+	}
+
+	data := account.Value.Data.GetBinary()
+	if len(data) <= mintDecimalsOffset {
+		return Info{}, fmt.Errorf("mint %s has invalid account data", mint)
+	}
+
+	info := Info{
+		Symbol:       symbol,
+		Mint:         mint,
+		Decimals:     data[mintDecimalsOffset],
+		TokenProgram: owner,
+	}
+
+	r.mu.Lock()
+	r.tokens[mintStr] = info
+	r.mu.Unlock()
+
+	return info, nil
+}
+
+// MintAddress returns the mint address for a known symbol, or the input itself if it is already a mint
+func MintAddress(symbolOrMint string) string {
+	_, mint := lookupSymbol(symbolOrMint)
+	return mint
+}
+
+// Symbol returns the symbol of a known mint, or a shortened mint address for unknown ones
+func Symbol(mint string) string {
+	symbol, _ := lookupSymbol(mint)
+	return symbol
+}
+
+// lookupSymbol returns the symbol and mint for a symbol or mint address.
+// Unknown mints use a shortened mint address as their symbol.
+func lookupSymbol(symbolOrMint string) (string, string) {
+	symbolOrMint = strings.TrimSpace(symbolOrMint)
+	upper := strings.ToUpper(symbolOrMint)
+	if mint, ok := KnownMints[upper]; ok {
+		return upper, mint
+	}
+
+	for symbol, mint := range KnownMints {
+		if mint == symbolOrMint {
+			return symbol, mint
+		}
+	}
+
+	short := symbolOrMint
+	if len(short) > 8 {
+		short = short[:4] + ".." + short[len(short)-4:]
+	}
+	return short, symbolOrMint
+}
@@ -0,0 +1,15 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+func TestKnownMintsAreValidPublicKeys(t *testing.T) {
+	for symbol, mint := range KnownMints {
+		if _, err := solana.PublicKeyFromBase58(mint); err != nil {
+			t.Errorf("KnownMints[%q] = %q is not a valid mint: %v", symbol, mint, err)
+		}
+	}
+}
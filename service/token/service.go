@@ -0,0 +1,364 @@
+// package token manages the wallet's SPL token accounts
+package token
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"swap/pkg/logger"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// WrappedSOLMint is the mint address for wrapped SOL, owned by the SPL Token program
+const WrappedSOLMint = "So11111111111111111111111111111111111111112"
+
+// WrappedSOL2022Mint is the native mint of the Token-2022 program, a separate wrapped SOL mint
+const WrappedSOL2022Mint = "9pan9bMn5HatX4EJdBwg9VgCa7Uz5HL8N1m5D3NdXejP"
+
+// wrappedSOLMints maps each wrapped SOL mint to the token program that owns it
+var wrappedSOLMints = []struct {
+	mint    string
+	program solana.PublicKey
+}{
+	{WrappedSOLMint, solana.TokenProgramID},
+	{WrappedSOL2022Mint, solana.Token2022ProgramID},
+}
+
+const (
+	// createIdempotentInstruction is the associated token account program
+	// instruction that creates an account only if it doesn't exist yet
+	createIdempotentInstruction = 1
+
+	// closeAccountInstruction is the token program instruction that closes an account
+	// and sends its lamports (including any wrapped SOL) to a destination
+	closeAccountInstruction = 9
+
+	// confirmationTimeout bounds how long we wait for an account transaction to confirm
+	confirmationTimeout = 60 * time.Second
+)
+
+// ErrAccountNotFound is returned when an account does not exist on chain
+var ErrAccountNotFound = errors.New("account not found")
+
+// Account describes an associated token account owned by the wallet
+type Account struct {
+	Mint         solana.PublicKey
+	Address      solana.PublicKey
+	TokenProgram solana.PublicKey
+	Exists       bool
+}
+
+// Service creates, inspects and closes the wallet's token accounts
+type Service struct {
+	client     *rpc.Client
+	privateKey solana.PrivateKey
+	publicKey  solana.PublicKey
+	autoCreate bool
+
+	// A mint's token program never changes and token accounts are only created, so both
+	// are cached; missing accounts are checked again until they exist
+	cacheMu       sync.Mutex
+	tokenPrograms map[solana.PublicKey]solana.PublicKey
+	existing      map[solana.PublicKey]Account
+}
+
+// NewService creates a new token account service. When autoCreate is false,
+// the user is asked for confirmation on stdin before any account is created.
+func NewService(client *rpc.Client, privateKey solana.PrivateKey, autoCreate bool) *Service {
+	return &Service{
+		client:     client,
+		privateKey: privateKey,
+		publicKey:  privateKey.PublicKey(),
+		autoCreate: autoCreate,
+
+		tokenPrograms: make(map[solana.PublicKey]solana.PublicKey),
+		existing:      make(map[solana.PublicKey]Account),
+	}
+}
+
+// GetTokenProgram returns the token program (SPL Token or Token-2022) that owns a mint
+func (s *Service) GetTokenProgram(ctx context.Context, mint solana.PublicKey) (solana.PublicKey, error) {
+	s.cacheMu.Lock()
+	program, ok := s.tokenPrograms[mint]
+	s.cacheMu.Unlock()
+	if ok {
+		return program, nil
+	}
+
+	info, err := s.client.GetAccountInfo(ctx, mint)
+	if err != nil {
+		if errors.Is(err, rpc.ErrNotFound) {
+			return solana.PublicKey{}, fmt.Errorf("mint %s: %w", mint, ErrAccountNotFound)
+		}
+		return solana.PublicKey{}, fmt.Errorf("failed to get mint account %s: %v", mint, err)
+	}
+
+	owner := info.Value.Owner
+	if !owner.Equals(solana.TokenProgramID) && !owner.Equals(solana.Token2022ProgramID) {
+		return solana.PublicKey{}, fmt.Errorf("mint %s is owned by unsupported program %s", mint, owner)
+	}
+
+	s.cacheMu.Lock()
+	s.tokenPrograms[mint] = owner
+	s.cacheMu.Unlock()
+	return owner, nil
+}
+
+// FindAccount derives the wallet's associated token account for a mint and checks whether it exists.
+// A missing account is reported through Exists; RPC failures are returned as errors.
+func (s *Service) FindAccount(ctx context.Context, mint solana.PublicKey) (Account, error) {
+	s.cacheMu.Lock()
+	account, ok := s.existing[mint]
+	s.cacheMu.Unlock()
+	if ok {
+		return account, nil
+	}
+
+	tokenProgram, err := s.GetTokenProgram(ctx, mint)
+	if err != nil {
+		return Account{}, err
+	}
+
+	address, err := FindAssociatedTokenAddress(s.publicKey, mint, tokenProgram)
+	if err != nil {
+		return Account{}, err
+	}
+
+	account = Account{
+		Mint:         mint,
+		Address:      address,
+		TokenProgram: tokenProgram,
+	}
+
+	_, err = s.client.GetAccountInfo(ctx, address)
+	switch {
+	case err == nil:
+		account.Exists = true
+		s.cacheMu.Lock()
+		s.existing[mint] = account
+		s.cacheMu.Unlock()
+	case errors.Is(err, rpc.ErrNotFound):
+		account.Exists = false
+	default:
+		return Account{}, fmt.Errorf("failed to get token account %s: %v", address, err)
+	}
+
+	return account, nil
+}
+
+// EnsureAccounts creates any missing associated token accounts for the given mints
+// in a single dedicated transaction and returns the resolved accounts
+func (s *Service) EnsureAccounts(ctx context.Context, mints ...string) ([]Account, error) {
+	accounts := make([]Account, 0, len(mints))
+	var instructions []solana.Instruction
+
+	for _, mintStr := range mints {
+		// Native SOL needs no token account; wrapped SOL accounts are temporary
+		if mintStr == WrappedSOLMint {
+			continue
+		}
+		// Its account would be closed as leftover wrapped SOL after every swap
+		if mintStr == WrappedSOL2022Mint {
+			return nil, fmt.Errorf("Token-2022 wrapped SOL %s can't be traded, use SOL", mintStr)
+		}
+
+		mint, err := solana.PublicKeyFromBase58(mintStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mint address %s: %v", mintStr, err)
+		}
+
+		account, err := s.FindAccount(ctx, mint)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+
+		if account.Exists {
+			logger.Info("Token account for mint %s exists: %s", mint, account.Address)
+			continue
+		}
+
+		logger.Warn("Token account for mint %s does not exist: %s", mint, account.Address)
+		instructions = append(instructions, newCreateIdempotentInstruction(s.publicKey, account))
+	}
+
+	if len(instructions) == 0 {
+		return accounts, nil
+	}
+
+	if !s.confirm(fmt.Sprintf("Create %d missing token account(s)? This costs rent for each account.", len(instructions))) {
+		return accounts, fmt.Errorf("creation of %d missing token account(s) was declined", len(instructions))
+	}
+
+	signature, err := s.sendAndConfirm(ctx, instructions)
+	if err != nil {
+		return accounts, fmt.Errorf("failed to create token accounts: %v", err)
+	}
+	logger.Info("Created %d token account(s) in transaction %s", len(instructions), signature)
+
+	// Remember the new accounts, the RPC may not report them until they are finalized
+	s.cacheMu.Lock()
+	for i := range accounts {
+		accounts[i].Exists = true
+		s.existing[accounts[i].Mint] = accounts[i]
+	}
+	s.cacheMu.Unlock()
+	return accounts, nil
+}
+
+// CloseWrappedSOL closes the wallet's wrapped SOL accounts, under both the SPL Token and
+// Token-2022 native mints, if any were left open, unwrapping their balance and reclaiming
+// the rent back to the wallet
+func (s *Service) CloseWrappedSOL(ctx context.Context) error {
+	for _, wrapped := range wrappedSOLMints {
+		if err := s.closeWrappedSOL(ctx, solana.MustPublicKeyFromBase58(wrapped.mint), wrapped.program); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeWrappedSOL closes the wallet's wrapped SOL account for one native mint
+func (s *Service) closeWrappedSOL(ctx context.Context, mint solana.PublicKey, tokenProgram solana.PublicKey) error {
+	address, err := FindAssociatedTokenAddress(s.publicKey, mint, tokenProgram)
+	if err != nil {
+		return err
+	}
+
+	info, err := s.client.GetAccountInfo(ctx, address)
+	if err != nil {
+		if errors.Is(err, rpc.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get wrapped SOL account: %v", err)
+	}
+
+	logger.Info("Closing wrapped SOL account %s to reclaim %.6f SOL", address, float64(info.Value.Lamports)/1e9)
+	instruction := solana.NewInstruction(
+		tokenProgram,
+		solana.AccountMetaSlice{
+			solana.Meta(address).WRITE(),
+			solana.Meta(s.publicKey).WRITE(),
+			solana.Meta(s.publicKey).SIGNER(),
+		},
+		[]byte{closeAccountInstruction},
+	)
+
+	signature, err := s.sendAndConfirm(ctx, []solana.Instruction{instruction})
+	if err != nil {
+		return fmt.Errorf("failed to close wrapped SOL account: %v", err)
+	}
+	logger.Info("Closed wrapped SOL account in transaction %s", signature)
+
+	s.cacheMu.Lock()
+	delete(s.existing, mint)
+	s.cacheMu.Unlock()
+	return nil
+}
+
+// FindAssociatedTokenAddress derives the associated token account for a wallet and mint
+// under the given token program, which allows Token-2022 mints to be supported
+func FindAssociatedTokenAddress(wallet, mint, tokenProgram solana.PublicKey) (solana.PublicKey, error) {
+	address, _, err := solana.FindProgramAddress(
+		[][]byte{wallet[:], tokenProgram[:], mint[:]},
+		solana.SPLAssociatedTokenAccountProgramID,
+	)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to derive token account: %v", err)
+	}
+	return address, nil
+}
+
+// newCreateIdempotentInstruction builds an associated token account creation instruction
+// that works for both the SPL Token and Token-2022 programs
+func newCreateIdempotentInstruction(payer solana.PublicKey, account Account) solana.Instruction {
+	return solana.NewInstruction(
+		solana.SPLAssociatedTokenAccountProgramID,
+		solana.AccountMetaSlice{
+			solana.Meta(payer).WRITE().SIGNER(),
+			solana.Meta(account.Address).WRITE(),
+			solana.Meta(payer),
+			solana.Meta(account.Mint),
+			solana.Meta(solana.SystemProgramID),
+			solana.Meta(account.TokenProgram),
+		},
+		[]byte{createIdempotentInstruction},
+	)
+}
+
+// confirm asks the user to approve an action unless auto creation is enabled
+func (s *Service) confirm(prompt string) bool {
+	if s.autoCreate {
+		return true
+	}
+
+	fmt.Printf("%s [y/N]: ", prompt)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// sendAndConfirm signs and sends a transaction and waits until it is confirmed
+func (s *Service) sendAndConfirm(ctx context.Context, instructions []solana.Instruction) (solana.Signature, error) {
+	latest, err := s.client.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to get latest blockhash: %v", err)
+	}
+
+	tx, err := solana.NewTransaction(instructions, latest.Value.Blockhash, solana.TransactionPayer(s.publicKey))
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to build transaction: %v", err)
+	}
+
+	_, err = tx.Sign(func(key solana.PublicKey) *solana.PrivateKey {
+		if key.Equals(s.publicKey) {
+			return &s.privateKey
+		}
+		return nil
+	})
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to sign transaction: %v", err)
+	}
+
+	signature, err := s.client.SendTransaction(ctx, tx)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to send transaction: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, confirmationTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return signature, fmt.Errorf("transaction %s not confirmed: %v", signature, ctx.Err())
+		case <-ticker.C:
+			statuses, err := s.client.GetSignatureStatuses(ctx, false, signature)
+			if err != nil || len(statuses.Value) == 0 || statuses.Value[0] == nil {
+				continue
+			}
+			status := statuses.Value[0]
+			if status.Err != nil {
+				return signature, fmt.Errorf("transaction %s failed: %v", signature, status.Err)
+			}
+			if status.ConfirmationStatus == rpc.ConfirmationStatusConfirmed ||
+				status.ConfirmationStatus == rpc.ConfirmationStatusFinalized {
+				return signature, nil
+			}
+		}
+	}
+}
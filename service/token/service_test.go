@@ -0,0 +1,15 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+func TestWrappedSOLMintsAreValidPublicKeys(t *testing.T) {
+	for _, wrapped := range wrappedSOLMints {
+		if _, err := solana.PublicKeyFromBase58(wrapped.mint); err != nil {
+			t.Errorf("wrapped SOL mint %q is not a valid mint: %v", wrapped.mint, err)
+		}
+	}
+}